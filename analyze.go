@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// runAnalyze prints the odds and expected value of every option in a roll
+func runAnalyze(args []string) {
+	fs := newFlagSet("analyze", "[-points N] <dice>", "Show the odds and expected value of every option in a roll.\n\nExample: analyze -points 300 11256")
+	points := fs.Int("points", 0, "turn points already taken before this roll")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		log.Fatalf("Invalid input: exactly one roll is required")
+	}
+
+	dice, err := parseDice(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+	if *points < 0 || *points%50 != 0 {
+		log.Fatalf("Invalid input: -points must be a multiple of 50 and not negative, got %v", *points)
+	}
+
+	fmt.Printf("Roll %v with %v turn points\n", dice, *points)
+
+	opts, err := squelch.AnalyzeRoll(dice, *points)
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+	if len(opts) == 0 {
+		fmt.Println("\tSquelch! No scoring options.")
+	} else {
+		fmt.Printf("\t%-8v %6v %9v %9v  %v\n", "Take", "Points", "Dice left", "EV", "Then")
+		for _, o := range opts {
+			then := "roll"
+			if o.Stay {
+				then = "stay"
+			}
+			fmt.Printf("\t%-8v %6v %9v %9.1f  %v\n", o.DieValues, o.Points, o.DiceLeft, o.EV, then)
+		}
+	}
+
+	fmt.Println("Rolling again from here:")
+	fmt.Printf("\t%-5v %8v %9v\n", "Dice", "Squelch", "Roll EV")
+	for n := 6; n >= 1; n-- {
+		pos, err := squelch.Analyze(n, *points)
+		if err != nil {
+			log.Fatalf("Invalid input: %v", err)
+		}
+		fmt.Printf("\t%-5v %7.2f%% %9.1f\n", n, pos.SquelchProbability*100, pos.RollEV)
+	}
+}
+
+// parseDice validates and sorts a roll like 11256
+func parseDice(s string) (string, error) {
+	if len(s) < 1 || len(s) > 6 {
+		return "", fmt.Errorf("a roll is 1 to 6 dice, got %q", s)
+	}
+
+	d := strings.Split(s, "")
+	for _, v := range d {
+		if v < "1" || v > "6" {
+			return "", fmt.Errorf("dice values are 1 to 6, got %q", s)
+		}
+	}
+	sort.Strings(d)
+
+	return strings.Join(d, ""), nil
+}
@@ -0,0 +1,276 @@
+// Package config describes a tournament in a YAML or JSON file: who plays,
+// how the matches are set up and where the results go. JSON is read as YAML
+// so either works, durations are strings like "10s".
+//
+//	name: weekly
+//	gamesPerMatch: 20
+//	playersPerMatch: 2
+//	timeout: 5s
+//	seed: 42
+//	entrants:
+//	  - url: http://localhost:8080/
+//	  - url: https://example.com/bot
+//	    protocol: decide
+//	  - process: ./mybot
+//	    args: [--fast]
+//	  - builtin: threshold
+//	    options: {t: "450"}
+//	  - script: bots/careful.star
+//	outputs:
+//	  json: results.json
+//
+// Giving every entrant a team makes it a team tournament, where teammates'
+// scores count together and every team plays every other team. Entrants can
+// have a handicap, e.g. handicap: {startScore: 500, rerolls: 1}.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"gopkg.in/yaml.v3"
+)
+
+// the defaults for anything the file leaves out
+const (
+	DefaultGamesPerMatch = 10
+	DefaultTargetScore   = 5000
+	DefaultTimeout       = 10 * time.Second
+)
+
+// the only format and rule set so far
+const (
+	FormatRoundRobin = "round-robin"
+	RulesStandard    = "standard"
+)
+
+// Config is a tournament
+type Config struct {
+	Name string `yaml:"name"`
+	// Format is how matches are scheduled, only round-robin so far
+	Format        string `yaml:"format"`
+	GamesPerMatch int    `yaml:"gamesPerMatch"`
+	// PlayersPerMatch is 0 for every entrant in every match
+	PlayersPerMatch int `yaml:"playersPerMatch"`
+	TargetScore     int `yaml:"targetScore"`
+	// EveryEntrant is set by SetDefaults when PlayersPerMatch was left out,
+	// so every entrant still plays every match if some are dropped
+	EveryEntrant bool `yaml:"-"`
+	// Rules is the scoring rule set, only standard so far
+	Rules string `yaml:"rules"`
+	// Timeout is how long URL, process and script bots have to answer each
+	// callback
+	Timeout time.Duration `yaml:"timeout"`
+	// Concurrency is how many matches are played at once, 0 for all of them
+	Concurrency int `yaml:"concurrency"`
+	// Seed makes the dice and seating repeatable, best with a concurrency
+	// of 1. Random if not set.
+	Seed *int64 `yaml:"seed"`
+	// Auth is the JSON file of per-URL bot auth settings
+	Auth string `yaml:"auth"`
+	// AutoHandicap sets every entrant's handicap from how it has done in
+	// the tournaments in the Outputs.DB results file, instead of the
+	// entrants' own handicaps
+	AutoHandicap bool `yaml:"autoHandicap"`
+
+	Entrants []Entrant `yaml:"entrants"`
+	Outputs  Outputs   `yaml:"outputs"`
+}
+
+// Outputs are the files a tournament writes, each is optional
+type Outputs struct {
+	// JSON gets the final results
+	JSON string `yaml:"json"`
+	// Record gets every match event as JSON lines
+	Record string `yaml:"record"`
+	// Checkpoint gets every finished match so the tournament can be resumed
+	Checkpoint string `yaml:"checkpoint"`
+	// DB is the results file the tournament is added to
+	DB string `yaml:"db"`
+}
+
+// Load reads and validates the config file at path
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse reads and validates a YAML or JSON config
+func Parse(b []byte) (*Config, error) {
+	c := &Config{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		if err == io.EOF {
+			return nil, errors.New("config is empty")
+		}
+		return nil, err
+	}
+
+	c.SetDefaults()
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Teams are the entrant indexes of each team, in the order the teams first
+// appear, or nil if no entrant has a team
+func (c *Config) Teams() [][]int {
+	var teams [][]int
+	index := make(map[string]int)
+	for i, e := range c.Entrants {
+		if e.Team == "" {
+			continue
+		}
+		t, ok := index[e.Team]
+		if !ok {
+			t = len(teams)
+			index[e.Team] = t
+			teams = append(teams, nil)
+		}
+		teams[t] = append(teams[t], i)
+	}
+	return teams
+}
+
+// Handicaps are the entrants' handicaps in order, nil if none have one
+func (c *Config) Handicaps() []squelch.Handicap {
+	var res []squelch.Handicap
+	for i, e := range c.Entrants {
+		if e.Handicap == (Handicap{}) {
+			continue
+		}
+		if res == nil {
+			res = make([]squelch.Handicap, len(c.Entrants))
+		}
+		res[i] = squelch.Handicap(e.Handicap)
+	}
+	return res
+}
+
+// SetDefaults fills in everything left out
+func (c *Config) SetDefaults() {
+	if c.Format == "" {
+		c.Format = FormatRoundRobin
+	}
+	if c.Rules == "" {
+		c.Rules = RulesStandard
+	}
+	if c.GamesPerMatch == 0 {
+		c.GamesPerMatch = DefaultGamesPerMatch
+	}
+	if c.PlayersPerMatch == 0 {
+		c.PlayersPerMatch = len(c.Entrants)
+		c.EveryEntrant = true
+		// a team match is two teams
+		if teams := c.Teams(); teams != nil {
+			c.PlayersPerMatch = 2 * len(teams[0])
+			c.EveryEntrant = false
+		}
+	}
+	if c.TargetScore == 0 {
+		c.TargetScore = DefaultTargetScore
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+}
+
+// Validate returns every problem with the config at once
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	if c.Format != FormatRoundRobin {
+		fail("format %q isn't supported, use %v", c.Format, FormatRoundRobin)
+	}
+	if c.Rules != RulesStandard {
+		fail("rules %q aren't supported, use %v", c.Rules, RulesStandard)
+	}
+
+	if len(c.Entrants) < 2 {
+		fail("at least 2 entrants are required, got %v", len(c.Entrants))
+	}
+	for i, e := range c.Entrants {
+		if err := e.Validate(); err != nil {
+			fail("entrants[%v]: %v", i, err)
+		}
+		if err := e.Handicap.validate(c.TargetScore); err != nil {
+			fail("entrants[%v]: %v", i, err)
+		}
+	}
+	if c.AutoHandicap && c.Outputs.DB == "" {
+		fail("autoHandicap needs the outputs.db results file")
+	}
+
+	teams := c.Teams()
+	if teams != nil {
+		named := 0
+		for _, e := range c.Entrants {
+			if e.Team != "" {
+				named++
+			}
+		}
+		switch {
+		case named != len(c.Entrants):
+			fail("every entrant needs a team, or none of them, %v of %v have one", named, len(c.Entrants))
+		case len(teams) < 2:
+			fail("at least 2 teams are required, got %v", len(teams))
+		default:
+			for _, t := range teams {
+				if len(t) != len(teams[0]) {
+					fail("teams must be the same size, %v has %v entrants and %v has %v",
+						c.Entrants[t[0]].Team, len(t), c.Entrants[teams[0][0]].Team, len(teams[0]))
+					break
+				}
+			}
+			if c.PlayersPerMatch != 2*len(teams[0]) {
+				fail("playersPerMatch must be two teams (%v) in a team tournament, got %v", 2*len(teams[0]), c.PlayersPerMatch)
+			}
+		}
+		// a team's target is for its combined score, not one player's
+		for i, e := range c.Entrants {
+			if e.Handicap.TargetReduction != 0 {
+				fail("entrants[%v]: handicap targetReduction isn't supported in team tournaments", i)
+			}
+		}
+	}
+
+	if c.GamesPerMatch < 1 {
+		fail("gamesPerMatch must be at least 1, got %v", c.GamesPerMatch)
+	}
+	// with too few entrants the default is too few players, once is enough,
+	// and team tournaments checked it with the teams
+	if teams == nil && c.PlayersPerMatch < 2 && len(c.Entrants) >= 2 {
+		fail("playersPerMatch must be at least 2, got %v", c.PlayersPerMatch)
+	} else if teams == nil && len(c.Entrants) >= 2 && c.PlayersPerMatch > len(c.Entrants) {
+		fail("playersPerMatch cannot exceed the entrant count (%v), got %v", len(c.Entrants), c.PlayersPerMatch)
+	}
+	if c.TargetScore < 1 {
+		fail("targetScore must be positive, got %v", c.TargetScore)
+	}
+	if c.Timeout < 0 {
+		fail("timeout cannot be negative, got %v", c.Timeout)
+	}
+	if c.Concurrency < 0 {
+		fail("concurrency cannot be negative, got %v", c.Concurrency)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestParse_YAML(t *testing.T) {
+	c, err := Parse([]byte(`
+name: weekly
+gamesPerMatch: 20
+playersPerMatch: 2
+timeout: 5s
+concurrency: 4
+seed: 42
+entrants:
+  - url: http://localhost:8080/
+    timeout: 1s
+  - builtin: threshold
+    options: {t: "450"}
+  - builtin: random
+outputs:
+  json: results.json
+`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if c.Name != "weekly" || c.GamesPerMatch != 20 || c.PlayersPerMatch != 2 || c.Concurrency != 4 {
+		t.Errorf("Settings incorrect: %+v", c)
+	}
+	if c.Timeout != 5*time.Second || c.Entrants[0].Timeout != time.Second {
+		t.Errorf("Timeouts incorrect: %v %v", c.Timeout, c.Entrants[0].Timeout)
+	}
+	if c.Seed == nil || *c.Seed != 42 {
+		t.Errorf("Seed incorrect: %v", c.Seed)
+	}
+	if want, got := "threshold?t=450", c.Entrants[1].builtinSpec(); want != got {
+		t.Errorf("Builtin spec incorrect, want %v got %v", want, got)
+	}
+	if c.Outputs.JSON != "results.json" {
+		t.Errorf("Outputs incorrect: %+v", c.Outputs)
+	}
+
+	// defaults
+	if c.TargetScore != DefaultTargetScore || c.Format != FormatRoundRobin || c.Rules != RulesStandard {
+		t.Errorf("Defaults incorrect: %+v", c)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	c, err := Parse([]byte(`{
+	"targetScore": 3000,
+	"entrants": [{"builtin": "threshold"}, {"builtin": "maxev"}, {"builtin": "dice"}]
+}`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if c.TargetScore != 3000 || len(c.Entrants) != 3 {
+		t.Errorf("Settings incorrect: %+v", c)
+	}
+	// every entrant plays every match by default
+	if c.PlayersPerMatch != 3 {
+		t.Errorf("Players per match should default to the entrant count, got %v", c.PlayersPerMatch)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name, config string
+		errs         []string
+	}{
+		{"empty", ``, []string{"config is empty"}},
+		{"unknown field", `gamesPerMatch: 1
+entrant: []`, []string{"field entrant not found"}},
+		{"too few entrants", `entrants: [{builtin: threshold}]`, []string{"at least 2 entrants"}},
+		{"every problem", `
+format: swiss
+rules: house
+gamesPerMatch: -1
+playersPerMatch: 6
+entrants:
+  - builtin: threshold
+    url: http://localhost/
+  - url: ftp://localhost/
+  - builtin: nope
+  - builtin: threshold
+    args: [x]
+  - script: missing.star
+`, []string{
+			`format "swiss"`,
+			`rules "house"`,
+			"gamesPerMatch must be at least 1",
+			"playersPerMatch cannot exceed the entrant count (5), got 6",
+			"entrants[0]: set exactly one",
+			"entrants[1]: url",
+			"entrants[2]: unknown builtin",
+			"entrants[3]: args only apply",
+			"entrants[4]: script",
+		}},
+		{"bad option", `entrants: [{builtin: threshold, options: {x: "1"}}, {builtin: maxev}]`, []string{`unknown parameter "x"`}},
+		{"bad duration", `timeout: soon`, []string{"soon"}},
+		{"uneven teams", `entrants: [{builtin: threshold, team: a}, {builtin: maxev, team: b}, {builtin: random, team: b}]`, []string{"teams must be the same size"}},
+		{"missing team", `entrants: [{builtin: threshold, team: a}, {builtin: maxev, team: b}, {builtin: random}]`, []string{"every entrant needs a team"}},
+		{"team players per match", `
+playersPerMatch: 2
+entrants: [{builtin: threshold, team: a}, {builtin: maxev, team: b}, {builtin: random, team: a}, {builtin: dice, team: b}]
+`, []string{"playersPerMatch must be two teams (4)"}},
+		{"team target reduction", `
+entrants: [{builtin: threshold, team: a}, {builtin: maxev, team: b, handicap: {startScore: 500}}, {builtin: random, team: a, handicap: {targetReduction: 500}}, {builtin: dice, team: b}]
+`, []string{"entrants[2]: handicap targetReduction isn't supported in team tournaments"}},
+		{"bad protocol", `entrants: [{url: "http://localhost/", protocol: rpc}, {builtin: maxev, protocol: decide}]`, []string{
+			`entrants[0]: protocol "rpc"`,
+			"entrants[1]: protocol only applies",
+		}},
+		{"bad handicap", `
+targetScore: 5000
+entrants: [{builtin: threshold, handicap: {rerolls: -1}}, {builtin: maxev, handicap: {startScore: 5000}}, {builtin: random, handicap: {targetReduction: 6000}}]
+`, []string{
+			"entrants[0]: handicap cannot be negative",
+			"entrants[1]: handicap startScore must be below the target score (5000)",
+			"entrants[2]: handicap targetReduction",
+		}},
+		{"auto handicap without db", `
+autoHandicap: true
+entrants: [{builtin: threshold}, {builtin: maxev}]
+`, []string{"autoHandicap needs the outputs.db"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config))
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Error should mention %q, got:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Teams(t *testing.T) {
+	c, err := Parse([]byte(`
+entrants:
+  - {builtin: threshold, team: red}
+  - {builtin: maxev, team: blue}
+  - {builtin: random, team: red}
+  - {builtin: dice, team: blue}
+  - {builtin: endgame, team: green}
+  - {builtin: catchup, team: green}
+`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	teams := c.Teams()
+	if len(teams) != 3 || fmt.Sprint(teams) != "[[0 2] [1 3] [4 5]]" {
+		t.Errorf("Teams incorrect: %v", teams)
+	}
+	// two teams play each match
+	if c.PlayersPerMatch != 4 {
+		t.Errorf("Players per match should default to two teams, got %v", c.PlayersPerMatch)
+	}
+}
+
+func TestParse_Handicaps(t *testing.T) {
+	c, err := Parse([]byte(`
+entrants:
+  - builtin: threshold
+  - builtin: random
+    handicap: {startScore: 1000, targetReduction: 500, rerolls: 2}
+`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	h := c.Handicaps()
+	if len(h) != 2 || h[0] != (squelch.Handicap{}) || h[1] != (squelch.Handicap{StartScore: 1000, TargetReduction: 500, Rerolls: 2}) {
+		t.Errorf("Handicaps incorrect: %+v", h)
+	}
+
+	c.Entrants[1].Handicap = Handicap{}
+	if h := c.Handicaps(); h != nil {
+		t.Errorf("No handicaps should be nil, got %+v", h)
+	}
+}
+
+func TestConfig_Players(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "bot.star")
+	if err := os.WriteFile(script, []byte(`
+name = "s" + options["n"]
+def choose(dice, opts, state):
+    return (opts[0]["id"], True)
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Parse([]byte(`
+entrants:
+  - url: http://localhost:1/
+  - builtin: threshold
+    options: {t: "450"}
+  - script: ` + script + `
+    options: {n: "1"}
+`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	p, err := c.Players()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(p) != 3 {
+		t.Fatalf("Player count incorrect, want 3 got %v", len(p))
+	}
+
+	for i, want := range []string{"threshold?t=450", "s1"} {
+		info, err := p[i+1].Info()
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if info.Name != want {
+			t.Errorf("Name incorrect, want %v got %v", want, info.Name)
+		}
+	}
+}
+
+func TestParseEntrant(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Entrant
+	}{
+		{"http://localhost:8080/bot", Entrant{URL: "http://localhost:8080/bot"}},
+		{"https://example.com", Entrant{URL: "https://example.com"}},
+		{"cmd:./mybot --fast  -n 2", Entrant{Process: "./mybot", Args: []string{"--fast", "-n", "2"}}},
+		{"builtin:threshold?t=450", Entrant{Builtin: "threshold?t=450"}},
+		{"decide:https://example.com/bot", Entrant{URL: "https://example.com/bot", Protocol: ProtocolDecide}},
+		{"decide:cmd:./mybot", Entrant{Process: "./mybot", Protocol: ProtocolDecide}},
+	}
+	for _, tt := range tests {
+		got, err := ParseEntrant(tt.spec)
+		if err != nil {
+			t.Errorf("%v: %v", tt.spec, err)
+			continue
+		}
+		if got.URL != tt.want.URL || got.Process != tt.want.Process || got.Builtin != tt.want.Builtin || got.Protocol != tt.want.Protocol ||
+			strings.Join(got.Args, " ") != strings.Join(tt.want.Args, " ") {
+			t.Errorf("%v: want %+v got %+v", tt.spec, tt.want, got)
+		}
+	}
+
+	for _, spec := range []string{"threshold", "cmd:", "builtin:nope", "script:missing.star", "ftp://x", "decide:builtin:threshold"} {
+		if _, err := ParseEntrant(spec); err == nil {
+			t.Errorf("%v: expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/scriptbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// Entrant is a bot in the tournament, set exactly one of URL, Process,
+// Builtin or Script
+type Entrant struct {
+	// URL is a bot served over HTTP
+	URL string `yaml:"url"`
+	// Process is a bot program we run, with Args, that talks over stdio
+	Process string   `yaml:"process"`
+	Args    []string `yaml:"args"`
+	// Builtin is a reference bot like "threshold"
+	Builtin string `yaml:"builtin"`
+	// Script is a Starlark strategy script file
+	Script string `yaml:"script"`
+	// Options tune builtin and script bots, e.g. {t: "450"}
+	Options map[string]string `yaml:"options"`
+	// Timeout overrides the tournament timeout for URL, process and script
+	// bots
+	Timeout time.Duration `yaml:"timeout"`
+	// Protocol is how URL and process bots are called, ProtocolCallbacks by
+	// default
+	Protocol string `yaml:"protocol"`
+	// Team names the entrant's team in a team tournament
+	Team string `yaml:"team"`
+	// Handicap helps a weaker bot, none by default
+	Handicap Handicap `yaml:"handicap"`
+}
+
+// Handicap evens out a tournament between bots of different strengths
+type Handicap struct {
+	// StartScore is the score the bot starts each game with
+	StartScore int `yaml:"startScore"`
+	// TargetReduction lowers the score the bot needs to start the final round
+	TargetReduction int `yaml:"targetReduction"`
+	// Rerolls are how many squelches a game the bot rolls again
+	Rerolls int `yaml:"rerolls"`
+}
+
+// the protocols URL and process bots can speak
+const (
+	// ProtocolCallbacks calls the bot at every step of every match
+	ProtocolCallbacks = "callbacks"
+	// ProtocolDecide only asks the bot for its choices, each request has the
+	// whole game state
+	ProtocolDecide = "decide"
+)
+
+// ParseEntrant reads a bot spec from the command line:
+//
+//	http://host:port/path    a URL bot, https too
+//	cmd:./mybot --fast       a process bot, the command and its args
+//	builtin:threshold?t=450  a reference bot
+//	script:bots/careful.star a Starlark script bot
+//
+// A URL or cmd: spec after decide: is a bot that speaks ProtocolDecide, e.g.
+// decide:https://host/bot.
+func ParseEntrant(spec string) (Entrant, error) {
+	kind, rest := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		kind, rest = spec[:i], spec[i+1:]
+	}
+
+	if kind == "decide" {
+		e, err := ParseEntrant(rest)
+		if err != nil {
+			return e, err
+		}
+		e.Protocol = ProtocolDecide
+		if err := e.Validate(); err != nil {
+			return e, fmt.Errorf("bot %q: %v", spec, err)
+		}
+		return e, nil
+	}
+
+	var e Entrant
+	switch kind {
+	case "http", "https":
+		e.URL = spec
+	case "cmd":
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return e, fmt.Errorf("bot %q has no command", spec)
+		}
+		e.Process, e.Args = fields[0], fields[1:]
+	case "builtin":
+		e.Builtin = rest
+	case "script":
+		e.Script = rest
+	default:
+		return e, fmt.Errorf("unknown bot %q, expected a URL, cmd:<command>, builtin:<name> or script:<file>", spec)
+	}
+
+	if err := e.Validate(); err != nil {
+		return e, fmt.Errorf("bot %q: %v", spec, err)
+	}
+	return e, nil
+}
+
+// kind names what the entrant is, "" if it's not exactly one thing
+func (e Entrant) kind() string {
+	var kinds []string
+	for k, v := range map[string]string{"url": e.URL, "process": e.Process, "builtin": e.Builtin, "script": e.Script} {
+		if v != "" {
+			kinds = append(kinds, k)
+		}
+	}
+	if len(kinds) != 1 {
+		return ""
+	}
+	return kinds[0]
+}
+
+// Validate checks the entrant without starting or calling it
+func (e Entrant) Validate() error {
+	kind := e.kind()
+	if kind == "" {
+		return errors.New("set exactly one of url, process, builtin or script")
+	}
+
+	if len(e.Args) > 0 && kind != "process" {
+		return errors.New("args only apply to process bots")
+	}
+	if len(e.Options) > 0 && kind != "builtin" && kind != "script" {
+		return errors.New("options only apply to builtin and script bots")
+	}
+	if e.Timeout != 0 && kind == "builtin" {
+		return errors.New("timeout only applies to url, process and script bots")
+	}
+	if e.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative, got %v", e.Timeout)
+	}
+	if e.Protocol != "" && kind != "url" && kind != "process" {
+		return errors.New("protocol only applies to url and process bots")
+	}
+	if e.Protocol != "" && e.Protocol != ProtocolCallbacks && e.Protocol != ProtocolDecide {
+		return fmt.Errorf("protocol %q isn't supported, use %v or %v", e.Protocol, ProtocolCallbacks, ProtocolDecide)
+	}
+
+	switch kind {
+	case "url":
+		u, err := url.Parse(e.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url: %v", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("url %q must be http or https", e.URL)
+		}
+	case "builtin":
+		// cheap to make, and it checks the options
+		if _, err := localbot.NewBuiltinPlayer(e.builtinSpec()); err != nil {
+			return err
+		}
+	case "script":
+		if _, err := os.Stat(e.Script); err != nil {
+			return fmt.Errorf("script: %v", err)
+		}
+	}
+	return nil
+}
+
+// validate checks the handicap against the target score
+func (h Handicap) validate(targetScore int) error {
+	if h.StartScore < 0 || h.TargetReduction < 0 || h.Rerolls < 0 {
+		return fmt.Errorf("handicap cannot be negative, got %+v", h)
+	}
+	if h.StartScore >= targetScore {
+		return fmt.Errorf("handicap startScore must be below the target score (%v), got %v", targetScore, h.StartScore)
+	}
+	if h.TargetReduction >= targetScore {
+		return fmt.Errorf("handicap targetReduction must be below the target score (%v), got %v", targetScore, h.TargetReduction)
+	}
+	return nil
+}
+
+// builtinSpec adds the options to the builtin name as query parameters
+func (e Entrant) builtinSpec() string {
+	if len(e.Options) == 0 {
+		return e.Builtin
+	}
+
+	keys := make([]string, 0, len(e.Options))
+	for k := range e.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = url.QueryEscape(k) + "=" + url.QueryEscape(e.Options[k])
+	}
+
+	sep := "?"
+	if strings.Contains(e.Builtin, "?") {
+		sep = "&"
+	}
+	return e.Builtin + sep + strings.Join(params, "&")
+}
+
+// Player makes the bot. URL bots use their auth settings, if any, and URL,
+// process and script bots answer within timeout unless the entrant says
+// otherwise.
+func (e Entrant) Player(auths map[string]squelch.ApiAuth, timeout time.Duration) (squelch.Player, error) {
+	if e.Timeout != 0 {
+		timeout = e.Timeout
+	}
+
+	switch e.kind() {
+	case "url":
+		u, err := url.Parse(e.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url: %v", err)
+		}
+
+		p := squelch.NewApiPlayer(*u)
+		if a, ok := auths[u.String()]; ok {
+			if p, err = squelch.NewApiPlayerWithAuth(*u, a); err != nil {
+				return nil, err
+			}
+		}
+		if timeout > 0 {
+			p.SetTimeout(timeout)
+		}
+		p.SetDecideMode(e.Protocol == ProtocolDecide)
+		return p, nil
+	case "process":
+		p, err := squelch.NewProcessPlayer(e.Process, e.Args...)
+		if err != nil {
+			return nil, err
+		}
+		if timeout > 0 {
+			p.SetTimeout(timeout)
+		}
+		p.SetDecideMode(e.Protocol == ProtocolDecide)
+		return p, nil
+	case "builtin":
+		return localbot.NewBuiltinPlayer(e.builtinSpec())
+	case "script":
+		p, err := scriptbot.NewScriptPlayerWithOptions(e.Script, e.Options)
+		if err != nil {
+			return nil, err
+		}
+		if timeout > 0 {
+			p.SetTimeout(timeout)
+		}
+		return p, nil
+	}
+	return nil, errors.New("set exactly one of url, process, builtin or script")
+}
+
+// Players makes every entrant's bot in order
+func (c *Config) Players() ([]squelch.Player, error) {
+	auths, err := LoadAuth(c.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	p := make([]squelch.Player, len(c.Entrants))
+	for i, e := range c.Entrants {
+		if p[i], err = e.Player(auths, c.Timeout); err != nil {
+			return nil, fmt.Errorf("entrants[%v]: %v", i, err)
+		}
+	}
+	return p, nil
+}
+
+// LoadAuth reads the bot auth settings file, keyed by bot URL
+func LoadAuth(path string) (map[string]squelch.ApiAuth, error) {
+	auths := make(map[string]squelch.ApiAuth)
+	if path == "" {
+		return auths, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading auth file: %v", err)
+	}
+
+	if err := json.Unmarshal(b, &auths); err != nil {
+		return nil, fmt.Errorf("invalid auth file: %v", err)
+	}
+
+	return auths, nil
+}
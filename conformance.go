@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dlclark/squelchbot-arena-go/conformance"
+)
+
+// runValidate checks every given bot against the conformance scenarios
+func runValidate(args []string) {
+	fs := newFlagSet("validate", "[flags]", "Check each bot against the conformance scenarios, exits 1 if any fail.")
+	bots := addBotFlags(fs)
+	authPath := fs.String("auth", "", "JSON file of per-URL bot auth settings")
+	lf := newLogFlags(fs)
+	fs.Parse(args)
+
+	if err := lf.setup(); err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	if len(*bots) == 0 {
+		log.Fatalf("Invalid input: at least 1 bot is required")
+	}
+
+	p, err := makeBots(*bots, *authPath)
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	failed := 0
+	for i, bot := range p {
+		name := fmt.Sprintf("bot %v", i)
+		if info, err := bot.Info(); err == nil && info != nil {
+			name = info.Name
+		}
+
+		fmt.Printf("%v:\n", name)
+		for _, r := range conformance.Run(bot) {
+			switch {
+			case !r.Passed:
+				failed++
+				fmt.Printf("\tFAIL %-12v %v\n", r.Scenario, r.Err)
+			case r.Note != "":
+				fmt.Printf("\tPASS %-12v (%v)\n", r.Scenario, r.Note)
+			default:
+				fmt.Printf("\tPASS %v\n", r.Scenario)
+			}
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,373 @@
+// Package conformance drives any squelch.Player through scripted games to
+// confirm it handles every callback the arena can make.
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"github.com/segmentio/ksuid"
+)
+
+// Scenario is a single scripted situation a bot must handle. The bot plays
+// against a house player that scores 100 points and stays every turn.
+type Scenario struct {
+	Name        string
+	Description string
+	TargetScore int
+	Games       int
+	// HouseFirst has the house player take the first turn of each game
+	HouseFirst bool
+	// BotRolls are the rolls handed to the bot at the start of each of its
+	// turns, in order. A roll that doesn't match the number of dice being
+	// rolled is swapped for a scoring roll of the right size. Once the rolls
+	// run out the bot squelches.
+	BotRolls []string
+
+	check func(r *recorder) error
+}
+
+// Result is the outcome of running a single Scenario
+type Result struct {
+	Scenario string
+	Passed   bool
+	Err      error
+	// Note is extra detail about a passing scenario, e.g. if the bot's
+	// choices meant part of it wasn't exercised
+	Note string
+}
+
+// Scenarios returns the standard conformance scenarios
+func Scenarios() []Scenario {
+	return []Scenario{
+		{
+			Name:        "single-roll",
+			Description: "one scoring roll each turn, then game and match end",
+			TargetScore: 500,
+			Games:       1,
+			BotRolls:    []string{"112345"},
+			check:       checkEnds,
+		},
+		{
+			Name:        "multi-roll",
+			Description: "option IDs carry across several rolls in a turn",
+			TargetScore: 500,
+			Games:       1,
+			BotRolls:    []string{"112346", "11234", "1234", "155"},
+			check:       checkEnds,
+		},
+		{
+			Name:        "squelch",
+			Description: "the first roll of every turn squelches",
+			TargetScore: 300,
+			Games:       1,
+			BotRolls:    []string{"223466"},
+			check: func(r *recorder) error {
+				if r.squelches == 0 {
+					return errors.New("no squelch was delivered")
+				}
+				return checkEnds(r)
+			},
+		},
+		{
+			Name:        "rollover",
+			Description: "a triple double uses all six dice so continuing rolls six fresh dice",
+			TargetScore: 500,
+			Games:       1,
+			BotRolls:    []string{"223366", "112345"},
+			check: func(r *recorder) error {
+				if r.rollovers == 0 {
+					r.note = "bot stayed on the triple double, rollover not exercised"
+				}
+				return checkEnds(r)
+			},
+		},
+		{
+			Name:        "overtime",
+			Description: "the house reaches the target first so the bot plays a final round",
+			TargetScore: 100,
+			Games:       1,
+			HouseFirst:  true,
+			BotRolls:    []string{"112345"},
+			check: func(r *recorder) error {
+				if r.finalRoundTurns == 0 {
+					return errors.New("bot never saw a final round turn")
+				}
+				return checkEnds(r)
+			},
+		},
+		{
+			Name:        "multi-game",
+			Description: "several games in one match alternating who goes first",
+			TargetScore: 300,
+			Games:       3,
+			BotRolls:    []string{"111223", "122"},
+			check:       checkEnds,
+		},
+	}
+}
+
+// Run plays every standard scenario against the player
+func Run(p squelch.Player) []Result {
+	res := []Result{}
+	for _, s := range Scenarios() {
+		res = append(res, RunScenario(p, s))
+	}
+	return res
+}
+
+// RunScenario plays a single scenario against the player
+func RunScenario(p squelch.Player, s Scenario) Result {
+	res := Result{Scenario: s.Name}
+
+	r := &recorder{
+		Player:  p,
+		rolls:   s.BotRolls,
+		turnIDs: make(map[string]struct{}),
+	}
+	if err := runScenario(r, s); err != nil {
+		res.Err = err
+		return res
+	}
+
+	if s.check != nil {
+		res.Err = s.check(r)
+	}
+	res.Passed = res.Err == nil
+	res.Note = r.note
+
+	return res
+}
+
+func runScenario(r *recorder, s Scenario) error {
+	info, err := r.Info()
+	if err != nil {
+		return fmt.Errorf("info: %v", err)
+	}
+	if info == nil || info.Name == "" {
+		return errors.New("info: no name returned")
+	}
+
+	house := &housePlayer{}
+	players := []squelch.Player{r, house}
+	names := []string{info.Name, "House"}
+	matchID := "conformance-" + ksuid.New().String()
+
+	if err := r.MatchStart(matchID, 6, s.TargetScore, s.Games, 0, names, nil); err != nil {
+		return fmt.Errorf("match start: %v", err)
+	}
+
+	wins := make([]int, len(players))
+	for i := 0; i < s.Games; i++ {
+		start := i % 2
+		if s.HouseFirst {
+			start = 1
+		}
+
+		r.resetGame()
+		g := squelch.NewGame(players, s.TargetScore, matchID, fmt.Sprint(i+1), start)
+		g.SetRollFunc(func(diceCount int) string {
+			if house.isTurn() {
+				return houseRoll(diceCount)
+			}
+			return r.nextRoll(diceCount)
+		})
+
+		res, err := g.Run()
+		if err != nil {
+			if res.ErrIndex == 0 {
+				return fmt.Errorf("game %v: %v", i+1, err)
+			}
+			return fmt.Errorf("game %v: house player failed: %v", i+1, err)
+		}
+		if r.err != nil {
+			return fmt.Errorf("game %v: %v", i+1, r.err)
+		}
+		wins[res.WinnerIndex]++
+	}
+
+	if err := r.MatchEnd(matchID, wins); err != nil {
+		return fmt.Errorf("match end: %v", err)
+	}
+
+	return nil
+}
+
+// checkEnds confirms every game and the match ended cleanly
+func checkEnds(r *recorder) error {
+	if r.matchEnds != 1 {
+		return fmt.Errorf("expected 1 match end, got %v", r.matchEnds)
+	}
+	if r.gameEnds == 0 {
+		return errors.New("no game end was delivered")
+	}
+	return nil
+}
+
+// recorder sits between the game and the bot under test, scripting its rolls
+// and keeping track of what it was asked to do.
+type recorder struct {
+	squelch.Player
+
+	rolls   []string
+	rollIdx int
+
+	lastDice string
+	turnIDs  map[string]struct{}
+	err      error
+	note     string
+
+	squelches       int
+	rollovers       int
+	finalRoundTurns int
+	gameEnds        int
+	matchEnds       int
+}
+
+func (r *recorder) resetGame() {
+	r.turnIDs = make(map[string]struct{})
+}
+
+func (r *recorder) nextRoll(diceCount int) string {
+	idx := r.rollIdx
+	r.rollIdx++
+
+	if idx >= len(r.rolls) {
+		return squelchRoll(diceCount)
+	}
+	if len(r.rolls[idx]) != diceCount {
+		return scoringRoll(diceCount)
+	}
+	return r.rolls[idx]
+}
+
+func (r *recorder) GameStart(matchID, gameID string) error {
+	err := r.Player.GameStart(matchID, gameID)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("game start: %v", err)
+	}
+	return err
+}
+
+func (r *recorder) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, state squelch.GameState) error {
+	if _, ok := r.turnIDs[turnID]; ok && r.err == nil {
+		r.err = fmt.Errorf("turn ID %v was reused", turnID)
+	}
+	r.turnIDs[turnID] = struct{}{}
+	r.rollIdx = 0
+	r.lastDice = ""
+	if isFinalRound {
+		r.finalRoundTurns++
+	}
+
+	return r.Player.TurnStart(matchID, gameID, turnID, startPoints, otherPlayerTurns, isFinalRound, state)
+}
+
+func (r *recorder) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, state squelch.GameState) (*squelch.PlayerChoice, error) {
+	// six dice after a roll in the same turn is a rollover
+	if r.lastDice != "" && len(dieValues) == 6 {
+		r.rollovers++
+	}
+	r.lastDice = dieValues
+
+	return r.Player.Choose(matchID, gameID, turnID, dieValues, options, state)
+}
+
+func (r *recorder) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	r.squelches++
+	return r.Player.Squelch(matchID, gameID, turnID, dieValues)
+}
+
+func (r *recorder) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	r.gameEnds++
+	err := r.Player.GameEnd(matchID, gameID, finalPlayerTurns, winnerBotIndex)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("game end: %v", err)
+	}
+	return err
+}
+
+func (r *recorder) MatchEnd(matchID string, winsByBotIndex []int) error {
+	r.matchEnds++
+	return r.Player.MatchEnd(matchID, winsByBotIndex)
+}
+
+// housePlayer takes the highest option and stays every turn
+type housePlayer struct {
+	sync sync.Mutex
+	turn bool
+}
+
+func (p *housePlayer) isTurn() bool {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+	return p.turn
+}
+
+func (p *housePlayer) setTurn(t bool) {
+	p.sync.Lock()
+	p.turn = t
+	p.sync.Unlock()
+}
+
+func (p *housePlayer) Info() (*squelch.PlayerInfo, error) {
+	return &squelch.PlayerInfo{Name: "House"}, nil
+}
+
+func (p *housePlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
+	return nil
+}
+
+func (p *housePlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	return nil
+}
+
+func (p *housePlayer) GameStart(matchID, gameID string) error {
+	p.setTurn(false)
+	return nil
+}
+
+func (p *housePlayer) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	return nil
+}
+
+func (p *housePlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, state squelch.GameState) error {
+	p.setTurn(true)
+	return nil
+}
+
+func (p *housePlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, state squelch.GameState) (*squelch.PlayerChoice, error) {
+	// the house always stays, so the next roll belongs to the bot
+	p.setTurn(false)
+	return &squelch.PlayerChoice{TakeOptionID: options[0].ID, Stay: true}, nil
+}
+
+func (p *housePlayer) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	p.setTurn(false)
+	return nil
+}
+
+// houseRoll always scores exactly 100 points
+func houseRoll(diceCount int) string {
+	return sortDice("1" + "23462"[:diceCount-1])
+}
+
+// scoringRoll is a roll of diceCount dice with at least one scoring option
+func scoringRoll(diceCount int) string {
+	return sortDice("1" + "52346"[:diceCount-1])
+}
+
+// squelchRoll is a roll of diceCount dice with no scoring options
+func squelchRoll(diceCount int) string {
+	return "223466"[:diceCount]
+}
+
+func sortDice(dice string) string {
+	d := strings.Split(dice, "")
+	sort.Strings(d)
+	return strings.Join(d, "")
+}
@@ -0,0 +1,66 @@
+package conformance
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestRun_LocalBot(t *testing.T) {
+	for _, r := range Run(localbot.NewLocalBotPlayer("Local")) {
+		if !r.Passed {
+			t.Errorf("scenario %v failed: %v", r.Scenario, r.Err)
+		}
+	}
+}
+
+type badChooser struct {
+	*localbot.LocalBotPlayer
+}
+
+func (b badChooser) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, state squelch.GameState) (*squelch.PlayerChoice, error) {
+	// always answer with the first ID of the turn, which goes stale on the second roll
+	return &squelch.PlayerChoice{TakeOptionID: "0", Stay: false}, nil
+}
+
+type badGameEnd struct {
+	*localbot.LocalBotPlayer
+}
+
+func (b badGameEnd) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	return errors.New("500 Internal Server Error")
+}
+
+type badGameStart struct {
+	*localbot.LocalBotPlayer
+}
+
+func (b badGameStart) GameStart(matchID, gameID string) error {
+	return errors.New("500 Internal Server Error")
+}
+
+func TestRun_GameStartEndErrors(t *testing.T) {
+	for _, p := range []squelch.Player{badGameStart{localbot.NewLocalBotPlayer("Bad")}, badGameEnd{localbot.NewLocalBotPlayer("Bad")}} {
+		for _, r := range Run(p) {
+			if r.Passed {
+				t.Errorf("%T: expected scenario %v to fail", p, r.Scenario)
+			}
+		}
+	}
+}
+
+func TestRun_StaleOptionID(t *testing.T) {
+	var s Scenario
+	for _, sc := range Scenarios() {
+		if sc.Name == "multi-roll" {
+			s = sc
+		}
+	}
+
+	r := RunScenario(badChooser{localbot.NewLocalBotPlayer("Bad")}, s)
+	if r.Passed {
+		t.Fatalf("expected stale option IDs to fail the scenario")
+	}
+}
@@ -0,0 +1,330 @@
+// Package distrib spreads the matches of a tournament across worker
+// processes. The coordinator runs the tournament with a MatchRunner that
+// queues every match; workers poll the coordinator over HTTP, play the
+// matches against their own bot connections and post the results back.
+package distrib
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"github.com/segmentio/ksuid"
+)
+
+// maxAttempts is how many times a match is handed out before it's given up on
+const maxAttempts = 3
+
+// Job is a match for a worker to play
+type Job struct {
+	ID string `json:"id"`
+	// Entrants are the entrant indexes to play, in increasing order
+	Entrants []int `json:"entrants"`
+	// Names are the names of the Entrants, so a worker with different bots
+	// can refuse the job
+	Names         []string `json:"names"`
+	GamesPerMatch int      `json:"gamesPerMatch"`
+	TargetScore   int      `json:"targetScore"`
+}
+
+// Report is a worker's result for a job
+type Report struct {
+	JobID  string              `json:"jobId"`
+	Result squelch.MatchResult `json:"result"`
+	// Err is set if the worker couldn't play the match
+	Err string `json:"error,omitempty"`
+}
+
+// Coordinator hands out a tournament's matches to workers
+type Coordinator struct {
+	names         []string
+	gamesPerMatch int
+	targetScore   int
+	lease         time.Duration
+	poll          time.Duration
+	token         string
+
+	sync   sync.Mutex
+	jobs   map[string]*job
+	queue  []*job
+	ready  chan struct{}
+	closed bool
+}
+
+type job struct {
+	Job
+	attempts    int
+	leasedUntil time.Time
+	done        chan jobResult
+}
+
+type jobResult struct {
+	res squelch.MatchResult
+	err error
+}
+
+// NewCoordinator makes a coordinator for a tournament between the named
+// entrants. A worker that doesn't report back within the lease loses the
+// match to another worker. Workers must send the token as a bearer token,
+// an empty token lets any client in.
+func NewCoordinator(entrantNames []string, gamesPerMatch, targetScore int, lease time.Duration, token string) *Coordinator {
+	return &Coordinator{
+		names:         entrantNames,
+		gamesPerMatch: gamesPerMatch,
+		targetScore:   targetScore,
+		lease:         lease,
+		poll:          10 * time.Second,
+		token:         token,
+		jobs:          make(map[string]*job),
+		ready:         make(chan struct{}),
+	}
+}
+
+// RunMatch is the squelch.MatchRunner that queues a match for the workers
+// and waits for its result
+func (c *Coordinator) RunMatch(ctx context.Context, entrants []int) (squelch.MatchResult, error) {
+	j := &job{
+		Job: Job{
+			ID:            ksuid.New().String(),
+			Entrants:      append([]int(nil), entrants...),
+			Names:         make([]string, len(entrants)),
+			GamesPerMatch: c.gamesPerMatch,
+			TargetScore:   c.targetScore,
+		},
+		done: make(chan jobResult, 1),
+	}
+	for i, e := range entrants {
+		j.Names[i] = c.names[e]
+	}
+
+	c.sync.Lock()
+	c.jobs[j.ID] = j
+	c.enqueue(j)
+	c.sync.Unlock()
+
+	select {
+	case r := <-j.done:
+		return r.res, r.err
+	case <-ctx.Done():
+		c.sync.Lock()
+		c.remove(j)
+		c.sync.Unlock()
+		return squelch.MatchResult{}, ctx.Err()
+	}
+}
+
+// Close tells the workers polling for matches that there won't be any more
+func (c *Coordinator) Close() {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.closed = true
+	c.wake()
+}
+
+// Handler returns the http handler the workers talk to
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/work", c.handleWork)
+	mux.HandleFunc("/result", c.handleResult)
+	return c.authorize(mux)
+}
+
+// authorize turns away the requests without the coordinator's token
+func (c *Coordinator) authorize(h http.Handler) http.Handler {
+	want := []byte("Bearer " + c.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// handleWork long polls for the next match. It answers 204 when there's
+// nothing to do yet and 410 once the tournament is over.
+func (c *Coordinator) handleWork(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST for work", http.StatusMethodNotAllowed)
+		return
+	}
+
+	timeout := time.NewTimer(c.poll)
+	defer timeout.Stop()
+
+	for {
+		c.sync.Lock()
+		if c.closed {
+			c.sync.Unlock()
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		c.requeueExpired()
+		if len(c.queue) > 0 {
+			j := c.queue[0]
+			c.queue = c.queue[1:]
+			j.attempts++
+			j.leasedUntil = time.Now().Add(c.lease)
+			c.sync.Unlock()
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(j.Job)
+			return
+		}
+		ready := c.ready
+		expiry := c.nextExpiry()
+		c.sync.Unlock()
+
+		// look again when the next lease runs out
+		var expired <-chan time.Time
+		var t *time.Timer
+		if !expiry.IsZero() {
+			t = time.NewTimer(time.Until(expiry))
+			expired = t.C
+		}
+
+		select {
+		case <-ready:
+		case <-expired:
+		case <-timeout.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+func (c *Coordinator) handleResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST a result", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var rep Report
+	if err := json.NewDecoder(r.Body).Decode(&rep); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	j, ok := c.jobs[rep.JobID]
+	if !ok {
+		// already finished by another worker after the lease ran out
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if rep.Err == "" {
+		rep.Err = c.checkResult(j, rep.Result)
+	}
+
+	if rep.Err != "" {
+		if j.attempts >= maxAttempts {
+			c.remove(j)
+			j.done <- jobResult{err: fmt.Errorf("gave up after %v attempts: %v", j.attempts, rep.Err)}
+		} else if !j.leasedUntil.IsZero() {
+			c.enqueue(j)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	c.remove(j)
+	j.done <- jobResult{res: rep.Result}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkResult returns why a worker's result can't be for the job, empty if it can
+func (c *Coordinator) checkResult(j *job, res squelch.MatchResult) string {
+	if matchKey(res.Entrants) != matchKey(j.Entrants) {
+		return fmt.Sprintf("result for %v instead of %v", res.Entrants, j.Entrants)
+	}
+	if len(res.Wins) != len(j.Entrants) || len(res.Errors) != len(j.Entrants) {
+		return fmt.Sprintf("result has %v wins and %v errors for %v entrants", len(res.Wins), len(res.Errors), len(j.Entrants))
+	}
+
+	// every game has a winner or a bot to blame, and each bot may also have
+	// failed the match start
+	wins, errs := 0, 0
+	for i := range res.Wins {
+		if res.Wins[i] < 0 || res.Errors[i] < 0 {
+			return fmt.Sprintf("result has negative counts %v %v", res.Wins, res.Errors)
+		}
+		wins += res.Wins[i]
+		errs += res.Errors[i]
+	}
+	if wins > j.GamesPerMatch || wins+errs < j.GamesPerMatch || wins+errs > j.GamesPerMatch+len(j.Entrants) {
+		return fmt.Sprintf("result has %v wins and %v errors for %v games", wins, errs, j.GamesPerMatch)
+	}
+	return ""
+}
+
+// enqueue puts a job at the back of the queue and wakes the waiting workers
+func (c *Coordinator) enqueue(j *job) {
+	j.leasedUntil = time.Time{}
+	c.queue = append(c.queue, j)
+	c.wake()
+}
+
+// requeueExpired hands out again every match whose worker has gone quiet
+func (c *Coordinator) requeueExpired() {
+	now := time.Now()
+	for _, j := range c.jobs {
+		if j.leasedUntil.IsZero() || now.Before(j.leasedUntil) {
+			continue
+		}
+		if j.attempts >= maxAttempts {
+			c.remove(j)
+			j.done <- jobResult{err: fmt.Errorf("gave up after %v attempts: %w", j.attempts, errLeaseExpired)}
+			continue
+		}
+		c.enqueue(j)
+	}
+}
+
+// nextExpiry returns when the next lease runs out, zero if nothing is leased
+func (c *Coordinator) nextExpiry() time.Time {
+	var next time.Time
+	for _, j := range c.jobs {
+		if !j.leasedUntil.IsZero() && (next.IsZero() || j.leasedUntil.Before(next)) {
+			next = j.leasedUntil
+		}
+	}
+	return next
+}
+
+var errLeaseExpired = errors.New("worker didn't report back in time")
+
+// remove forgets a job wherever it is
+func (c *Coordinator) remove(j *job) {
+	delete(c.jobs, j.ID)
+	for i, q := range c.queue {
+		if q == j {
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			break
+		}
+	}
+}
+
+// wake releases every worker waiting for work
+func (c *Coordinator) wake() {
+	close(c.ready)
+	c.ready = make(chan struct{})
+}
+
+func matchKey(entrants []int) string {
+	return fmt.Sprint(entrants)
+}
@@ -0,0 +1,234 @@
+package distrib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func bots(t *testing.T, specs ...string) []squelch.Player {
+	p := make([]squelch.Player, len(specs))
+	for i, s := range specs {
+		b, err := localbot.NewBuiltinPlayer(s)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		p[i] = b
+	}
+	return p
+}
+
+func names(t *testing.T, players []squelch.Player) []string {
+	n := make([]string, len(players))
+	for i, p := range players {
+		info, _ := p.Info()
+		n[i] = info.Name
+	}
+	return n
+}
+
+// runWorkers starts workers with their own bots and returns a channel that
+// closes once they've all stopped
+func runWorkers(t *testing.T, url, token string, workers [][]squelch.Player) chan struct{} {
+	done := make(chan struct{})
+	remaining := make(chan struct{}, len(workers))
+	for _, w := range workers {
+		go func(w *Worker) {
+			if err := w.Run(context.Background(), 2); err != nil {
+				t.Errorf("Worker error: %v", err)
+			}
+			remaining <- struct{}{}
+			if len(remaining) == cap(remaining) {
+				close(done)
+			}
+		}(NewWorker(url, token, w))
+	}
+	return done
+}
+
+func TestDistributed(t *testing.T) {
+	specs := []string{"threshold", "maxev", "endgame", "dice"}
+	entrants := bots(t, specs...)
+
+	c := NewCoordinator(names(t, entrants), 4, 1000, time.Minute, "secret")
+	ts := httptest.NewServer(c.Handler())
+	defer ts.Close()
+
+	stopped := runWorkers(t, ts.URL, "secret", [][]squelch.Player{bots(t, specs...), bots(t, specs...)})
+
+	tr := squelch.NewTournament(4, 2, 1000, entrants)
+	tr.SetMatchRunner(c.RunMatch)
+	r, err := tr.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	c.Close()
+
+	if r.Incomplete || r.MatchesFinished != 6 {
+		t.Errorf("Results incomplete, %v matches finished", r.MatchesFinished)
+	}
+	wins := 0
+	for _, p := range r.Points {
+		if p.Matches != 3 {
+			t.Errorf("Entrant %v matches incorrect, want 3 got %v", p.EntrantIndex, p.Matches)
+		}
+		wins += p.TotalWins
+	}
+	if wins != 6*4 {
+		t.Errorf("Total wins incorrect, want 24 got %v", wins)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Workers didn't stop after the tournament")
+	}
+}
+
+func TestCoordinator_LeaseExpires(t *testing.T) {
+	specs := []string{"threshold", "maxev"}
+	entrants := bots(t, specs...)
+
+	c := NewCoordinator(names(t, entrants), 2, 1000, 50*time.Millisecond, "")
+	ts := httptest.NewServer(c.Handler())
+	defer ts.Close()
+
+	// a worker takes the match and disappears
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		res, err := http.Post(ts.URL+"/work", "application/json", nil)
+		if err == nil {
+			res.Body.Close()
+		}
+		// then a real one comes along
+		time.Sleep(20 * time.Millisecond)
+		runWorkers(t, ts.URL, "", [][]squelch.Player{bots(t, specs...)})
+	}()
+	defer c.Close()
+
+	res, err := c.RunMatch(context.Background(), []int{0, 1})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if res.Wins[0]+res.Wins[1] != 2 {
+		t.Errorf("Result incorrect, got %+v", res)
+	}
+}
+
+func TestCoordinator_WrongBots(t *testing.T) {
+	entrants := bots(t, "threshold", "maxev")
+
+	c := NewCoordinator(names(t, entrants), 2, 1000, time.Minute, "")
+	ts := httptest.NewServer(c.Handler())
+	defer ts.Close()
+	runWorkers(t, ts.URL, "", [][]squelch.Player{bots(t, "threshold", "dice")})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.RunMatch(ctx, []int{0, 1}); err == nil || err == context.DeadlineExceeded {
+		t.Errorf("expected the coordinator to give up, got %v", err)
+	}
+}
+
+// post sends a request to the coordinator with the token and returns its status
+func post(url, token string, body interface{}) (int, error) {
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	return res.StatusCode, nil
+}
+
+func TestCoordinator_Token(t *testing.T) {
+	entrants := bots(t, "threshold", "maxev")
+
+	c := NewCoordinator(names(t, entrants), 2, 1000, time.Minute, "secret")
+	c.Close()
+	ts := httptest.NewServer(c.Handler())
+	defer ts.Close()
+
+	for _, tok := range []string{"", "wrong"} {
+		for _, ep := range []string{"/work", "/result"} {
+			if code, err := post(ts.URL+ep, tok, Report{}); code != http.StatusUnauthorized {
+				t.Errorf("%v with token %q: want 401 got %v %v", ep, tok, code, err)
+			}
+		}
+	}
+	if code, err := post(ts.URL+"/work", "secret", nil); code != http.StatusGone {
+		t.Errorf("/work with the token: want 410 got %v %v", code, err)
+	}
+}
+
+func TestCoordinator_ResultTotals(t *testing.T) {
+	entrants := bots(t, "threshold", "maxev")
+	n := names(t, entrants)
+
+	c := NewCoordinator(n, 2, 1000, time.Minute, "")
+	ts := httptest.NewServer(c.Handler())
+	defer ts.Close()
+	defer c.Close()
+
+	// a worker claims more games were won than were played, every time
+	go func() {
+		for i := 0; i < maxAttempts; i++ {
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/work", nil)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("Error: %v", err)
+				return
+			}
+			var j Job
+			json.NewDecoder(res.Body).Decode(&j)
+			res.Body.Close()
+
+			post(ts.URL+"/result", "", Report{JobID: j.ID, Result: squelch.MatchResult{
+				Entrants: j.Entrants, Names: j.Names, Wins: []int{5, 0}, Errors: []int{0, 0},
+			}})
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.RunMatch(ctx, []int{0, 1}); err == nil || err == context.DeadlineExceeded {
+		t.Errorf("expected the coordinator to give up, got %v", err)
+	}
+}
+
+func TestCoordinator_CheckResult(t *testing.T) {
+	c := NewCoordinator([]string{"a", "b"}, 2, 1000, time.Minute, "")
+	j := &job{Job: Job{Entrants: []int{0, 1}, GamesPerMatch: 2}}
+
+	for _, tt := range []struct {
+		wins, errs []int
+		ok         bool
+	}{
+		{[]int{1, 1}, []int{0, 0}, true},
+		{[]int{1, 0}, []int{0, 1}, true},
+		// a failed match start is counted on top of the games
+		{[]int{0, 2}, []int{1, 0}, true},
+		{[]int{2, 1}, []int{0, 0}, false},
+		{[]int{1, 0}, []int{0, 0}, false},
+		{[]int{0, 0}, []int{3, 2}, false},
+		{[]int{3, -1}, []int{0, 0}, false},
+		{[]int{2}, []int{0}, false},
+	} {
+		msg := c.checkResult(j, squelch.MatchResult{Entrants: []int{0, 1}, Wins: tt.wins, Errors: tt.errs})
+		if (msg == "") != tt.ok {
+			t.Errorf("wins %v errors %v: want ok %v got %q", tt.wins, tt.errs, tt.ok, msg)
+		}
+	}
+}
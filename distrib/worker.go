@@ -0,0 +1,196 @@
+package distrib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// retryDelay is how long a worker waits before asking an unreachable
+// coordinator again
+const retryDelay = time.Second
+
+// maxRetries is how many times in a row a worker asks an unreachable
+// coordinator before deciding it's gone
+const maxRetries = 30
+
+// Worker plays the matches a coordinator hands out
+type Worker struct {
+	coordinator string
+	token       string
+	entrants    []squelch.Player
+	client      *http.Client
+}
+
+// NewWorker makes a worker for the coordinator at the base URL, sending it
+// the coordinator's token. The entrants must be the same bots, in the same
+// order, as the coordinator's.
+func NewWorker(coordinator, token string, entrants []squelch.Player) *Worker {
+	return &Worker{
+		coordinator: strings.TrimSuffix(coordinator, "/"),
+		token:       token,
+		entrants:    entrants,
+		// longer than the coordinator's long poll
+		client: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Run plays matches, parallelism at a time, until the coordinator says the
+// tournament is over, the coordinator can't be reached or ctx is canceled
+func (w *Worker) Run(ctx context.Context, parallelism int) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	wg := sync.WaitGroup{}
+	errs := make([]error, parallelism)
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = w.loop(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Worker) loop(ctx context.Context) error {
+	retries := 0
+	for ctx.Err() == nil {
+		j, over, err := w.next(ctx)
+		if over {
+			return nil
+		}
+		if errors.Is(err, errUnauthorized) {
+			return err
+		}
+		if err != nil {
+			retries++
+			if retries >= maxRetries {
+				return fmt.Errorf("giving up on the coordinator: %w", err)
+			}
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		retries = 0
+		if j == nil {
+			continue
+		}
+
+		rep := Report{JobID: j.ID}
+		rep.Result, err = w.play(ctx, j)
+		if err != nil {
+			rep.Err = err.Error()
+		}
+		// if the result is lost the lease runs out and the match is played again
+		w.post("/result", rep)
+	}
+	return ctx.Err()
+}
+
+// next asks for a match. over is set once the coordinator has no more.
+func (w *Worker) next(ctx context.Context) (j *Job, over bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.coordinator+"/work", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	w.authorize(req)
+	res, err := w.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		j = &Job{}
+		return j, false, json.NewDecoder(res.Body).Decode(j)
+	case http.StatusNoContent:
+		return nil, false, nil
+	case http.StatusGone:
+		return nil, true, nil
+	case http.StatusUnauthorized:
+		return nil, false, errUnauthorized
+	}
+	b, _ := io.ReadAll(res.Body)
+	return nil, false, fmt.Errorf("coordinator: %v %s", res.Status, b)
+}
+
+var errUnauthorized = errors.New("the coordinator turned down our token")
+
+// play runs the match locally against our own bots
+func (w *Worker) play(ctx context.Context, j *Job) (squelch.MatchResult, error) {
+	if len(j.Names) != len(j.Entrants) {
+		return squelch.MatchResult{}, fmt.Errorf("job has %v names for %v entrants", len(j.Names), len(j.Entrants))
+	}
+	for i, e := range j.Entrants {
+		if e < 0 || e >= len(w.entrants) {
+			return squelch.MatchResult{}, fmt.Errorf("worker has no entrant %v", e)
+		}
+		info, err := w.entrants[e].Info()
+		if err != nil {
+			return squelch.MatchResult{}, err
+		}
+		if info.Name != j.Names[i] {
+			return squelch.MatchResult{}, fmt.Errorf("worker has bot %q for entrant %v, want %q", info.Name, e, j.Names[i])
+		}
+	}
+
+	t := squelch.NewTournament(j.GamesPerMatch, len(j.Entrants), j.TargetScore, w.entrants)
+	return t.PlayMatch(ctx, j.Entrants)
+}
+
+func (w *Worker) post(endpoint string, req interface{}) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r, err := http.NewRequest(http.MethodPost, w.coordinator+endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	w.authorize(r)
+
+	res, err := w.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("coordinator: %v %s", res.Status, body)
+	}
+	return nil
+}
+
+// authorize adds the coordinator's token to a request
+func (w *Worker) authorize(r *http.Request) {
+	if w.token != "" {
+		r.Header.Set("Authorization", "Bearer "+w.token)
+	}
+}
@@ -1,22 +1,92 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/dlclark/squelchbot-arena-go/config"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
 )
 
-type urls []url.URL
+// newFlagSet makes a command's flags with help that shows how to use it
+func newFlagSet(name, usage, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %v %v %v\n\n%v\n\nFlags:\n", filepath.Base(os.Args[0]), name, usage, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// specs are bot specs from the command line, e.g. builtin:threshold, in the
+// order given
+type specs []string
+
+// entrants parses every spec
+func (s specs) entrants() ([]config.Entrant, error) {
+	entrants := make([]config.Entrant, len(s))
+	for i, spec := range s {
+		e, err := config.ParseEntrant(spec)
+		if err != nil {
+			return nil, err
+		}
+		entrants[i] = e
+	}
+	return entrants, nil
+}
 
-func (us *urls) String() string {
-	return fmt.Sprint(*us)
+// specFlag adds a bot spec to a list, prefix turns the older -script flag's
+// value into a spec
+type specFlag struct {
+	list   *specs
+	prefix string
 }
 
-func (us *urls) Set(value string) error {
-	u, err := url.Parse(value)
-	if err != nil {
-		return fmt.Errorf("invalid input URL format: %v", err)
+func (f specFlag) String() string {
+	if f.list == nil {
+		return ""
 	}
+	return fmt.Sprint(*f.list)
+}
 
-	*us = append(*us, *u)
+func (f specFlag) Set(value string) error {
+	spec := f.prefix + value
+	if _, err := config.ParseEntrant(spec); err != nil {
+		return err
+	}
+	*f.list = append(*f.list, spec)
 	return nil
 }
+
+// botUsage describes a bot spec flag
+const botUsage = "a URL, cmd:<command>, builtin:<name> or script:<file>, e.g. builtin:threshold?t=450. Put decide: before a URL or cmd: for a bot that speaks the stateless protocol. Repeatable."
+
+// addBotFlags adds the -bot flag, and the older -url and -script flags, for
+// the bots to play
+func addBotFlags(fs *flag.FlagSet) *specs {
+	s := &specs{}
+	fs.Var(specFlag{list: s}, "bot", "a bot to play: "+botUsage)
+	fs.Var(specFlag{list: s}, "url", "a client bot URL, the same as -bot <url>. Repeatable.")
+	fs.Var(specFlag{list: s, prefix: "script:"}, "script", "a Starlark strategy script file, the same as -bot script:<file>. Repeatable.")
+	return s
+}
+
+// addVsFlag adds the -vs flag for the opponents to play against
+func addVsFlag(fs *flag.FlagSet, defaults string) *specs {
+	s := &specs{}
+	fs.Var(specFlag{list: s}, "vs", "an opponent: "+botUsage+" Defaults to "+defaults+".")
+	return s
+}
+
+// makeBots makes the bots for the specs, URL bots use their settings in the
+// auth file if one is given
+func makeBots(s specs, authPath string) ([]squelch.Player, error) {
+	entrants, err := s.entrants()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config.Config{Auth: authPath, Entrants: entrants, Timeout: config.DefaultTimeout}
+	return cfg.Players()
+}
@@ -1,8 +1,17 @@
 module github.com/dlclark/squelchbot-arena-go
 
-go 1.14
+go 1.21
 
 require (
 	github.com/segmentio/ksuid v1.0.3
 	github.com/stretchr/testify v1.6.1
+	go.starlark.net v0.0.0-20231121155337-90ade8b19d09
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.1.0 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
 )
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/rlenv"
+)
+
+// runGym serves a training environment as line-delimited JSON over stdio
+func runGym(args []string) {
+	fs := newFlagSet("gym", "[flags]", "Serve a training environment as JSON lines over stdin and stdout, the agent plays\nagainst the -vs bots.")
+	vs := addVsFlag(fs, "builtin:threshold")
+	target := fs.Int("target", 5000, "target score")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed for the dice")
+	lf := newLogFlags(fs)
+	fs.Parse(args)
+
+	if err := lf.setup(); err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	if len(*vs) == 0 {
+		*vs = specs{"builtin:threshold"}
+	}
+
+	opponents, err := makeBots(*vs, "")
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	env, err := rlenv.New(*target, *seed, opponents...)
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	if err := rlenv.Serve(env, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("Error serving environment: %v", err)
+	}
+}
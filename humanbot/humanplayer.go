@@ -0,0 +1,221 @@
+package humanbot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+var _ squelch.Player = &HumanPlayer{}
+
+// HumanPlayer is a squelch player driven by a person at a terminal. Every
+// roll is drawn with its scoring options and the player types the number of
+// the option to take, adding "s" to stay.
+type HumanPlayer struct {
+	name string
+	in   *bufio.Scanner
+	out  io.Writer
+
+	sync       sync.Mutex
+	botIndex   int
+	botNames   []string
+	scores     map[int]int
+	turnPoints int
+}
+
+// NewHumanPlayer makes a player that reads choices from in and draws the
+// game to out
+func NewHumanPlayer(name string, in io.Reader, out io.Writer) *HumanPlayer {
+	return &HumanPlayer{
+		name:   name,
+		in:     bufio.NewScanner(in),
+		out:    out,
+		scores: make(map[int]int),
+	}
+}
+
+func (p *HumanPlayer) Info() (*squelch.PlayerInfo, error) {
+	return &squelch.PlayerInfo{Name: p.name}, nil
+}
+
+func (p *HumanPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	p.botIndex = yourBotIndex
+	p.botNames = botNames
+	fmt.Fprintf(p.out, "\n=== Match: %v games to %v points against %v ===\n", gameCount, maxPoints, p.others())
+	return nil
+}
+
+func (p *HumanPlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	fmt.Fprintln(p.out, "\n=== Match over ===")
+	for i, w := range winsByBotIndex {
+		fmt.Fprintf(p.out, "\t%-20v %v wins\n", p.nameOf(i), w)
+	}
+	return nil
+}
+
+func (p *HumanPlayer) GameStart(matchID, gameID string) error {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	p.scores = make(map[int]int)
+	fmt.Fprintf(p.out, "\n--- Game %v ---\n", gameID)
+	return nil
+}
+
+func (p *HumanPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	p.recordTurns(finalPlayerTurns)
+	fmt.Fprintf(p.out, "\n--- Game %v over, %v won ---\n", gameID, p.nameOf(winnerBotIndex))
+	p.drawScores()
+	return nil
+}
+
+func (p *HumanPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, state squelch.GameState) error {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	p.scores[p.botIndex] = startPoints
+	p.turnPoints = 0
+	p.recordTurns(otherPlayerTurns)
+
+	fmt.Fprintln(p.out)
+	for _, t := range otherPlayerTurns {
+		if len(t.Rolls) > 0 {
+			fmt.Fprintf(p.out, "%v: %v\n", p.nameOf(t.BotIndex), describeTurn(t))
+		}
+	}
+	p.drawScores()
+	if isFinalRound {
+		fmt.Fprintln(p.out, "*** FINAL ROUND: this is your last turn, beat the leader! ***")
+	}
+	fmt.Fprintln(p.out, "Your turn.")
+	return nil
+}
+
+func (p *HumanPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, state squelch.GameState) (*squelch.PlayerChoice, error) {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	fmt.Fprintf(p.out, "\nYou rolled %v with %v points this turn\n", drawDice(dieValues), p.turnPoints)
+	for i, o := range options {
+		fmt.Fprintf(p.out, "\t%v) take %-6v for %v points\n", i+1, o.DieValues, o.Points)
+	}
+
+	for {
+		fmt.Fprint(p.out, "Option number, add 's' to stay (e.g. 1 or 1s): ")
+		if !p.in.Scan() {
+			if err := p.in.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("no more input")
+		}
+
+		idx, stay, err := parseChoice(p.in.Text(), len(options))
+		if err != nil {
+			fmt.Fprintln(p.out, err)
+			continue
+		}
+
+		p.turnPoints += options[idx].Points
+		if stay {
+			fmt.Fprintf(p.out, "Staying with %v points.\n", p.turnPoints)
+		}
+		return &squelch.PlayerChoice{TakeOptionID: options[idx].ID, Stay: stay}, nil
+	}
+}
+
+func (p *HumanPlayer) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	fmt.Fprintf(p.out, "\nYou rolled %v\nSquelch! You lose %v points this turn.\n", drawDice(dieValues), p.turnPoints)
+	return nil
+}
+
+// recordTurns keeps the latest score of every player that has gone
+func (p *HumanPlayer) recordTurns(turns []squelch.PlayerTurn) {
+	for _, t := range turns {
+		if len(t.Rolls) > 0 {
+			p.scores[t.BotIndex] = t.EndPoints
+		}
+	}
+}
+
+func (p *HumanPlayer) drawScores() {
+	parts := []string{}
+	for i := range p.botNames {
+		parts = append(parts, fmt.Sprintf("%v %v", p.nameOf(i), p.scores[i]))
+	}
+	fmt.Fprintf(p.out, "Scores: %v\n", strings.Join(parts, " | "))
+}
+
+func (p *HumanPlayer) nameOf(botIndex int) string {
+	if botIndex == p.botIndex {
+		return "You"
+	}
+	if botIndex < len(p.botNames) {
+		return p.botNames[botIndex]
+	}
+	return fmt.Sprintf("Bot %v", botIndex)
+}
+
+func (p *HumanPlayer) others() string {
+	names := []string{}
+	for i := range p.botNames {
+		if i != p.botIndex {
+			names = append(names, p.nameOf(i))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// describeTurn summarizes another player's turn, e.g.
+// "rolled 112345 took 11, rolled 2346 squelch"
+func describeTurn(t squelch.PlayerTurn) string {
+	parts := []string{}
+	for _, r := range t.Rolls {
+		if r.Take == "" {
+			parts = append(parts, fmt.Sprintf("rolled %v squelch", r.DieValues))
+		} else {
+			parts = append(parts, fmt.Sprintf("rolled %v took %v", r.DieValues, r.Take))
+		}
+	}
+	return fmt.Sprintf("%v, %v -> %v", strings.Join(parts, ", "), t.StartPoints, t.EndPoints)
+}
+
+// drawDice shows each die in brackets, e.g. [1] [1] [2] [5] [6]
+func drawDice(dice string) string {
+	parts := make([]string, len(dice))
+	for i, d := range dice {
+		parts[i] = "[" + string(d) + "]"
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseChoice reads input like "2" or "2s" into an option index and stay
+func parseChoice(s string, optionCount int) (int, bool, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	stay := strings.HasSuffix(s, "s")
+	s = strings.TrimSpace(strings.TrimSuffix(s, "s"))
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > optionCount {
+		return 0, false, fmt.Errorf("pick an option from 1 to %v", optionCount)
+	}
+
+	return n - 1, stay, nil
+}
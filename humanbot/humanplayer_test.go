@@ -0,0 +1,80 @@
+package humanbot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestParseChoice(t *testing.T) {
+	cases := []struct {
+		in   string
+		idx  int
+		stay bool
+		ok   bool
+	}{
+		{"1", 0, false, true},
+		{"2s", 1, true, true},
+		{" 3 S ", 2, true, true},
+		{"4", 0, false, false},
+		{"0", 0, false, false},
+		{"x", 0, false, false},
+	}
+
+	for _, c := range cases {
+		idx, stay, err := parseChoice(c.in, 3)
+		if (err == nil) != c.ok {
+			t.Errorf("%q: error incorrect, got %v", c.in, err)
+			continue
+		}
+		if c.ok && (idx != c.idx || stay != c.stay) {
+			t.Errorf("%q: want %v %v got %v %v", c.in, c.idx, c.stay, idx, stay)
+		}
+	}
+}
+
+func TestHumanPlayer_Turn(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := NewHumanPlayer("Me", strings.NewReader("9\n2\n1s\n"), out)
+
+	p.MatchStart("m", 6, 5000, 1, 0, []string{"Me", "Bot"}, nil)
+	p.GameStart("m", "1")
+	p.TurnStart("m", "1", "2", 0, []squelch.PlayerTurn{{
+		BotIndex: 1, StartPoints: 0, EndPoints: 300,
+		Rolls: []squelch.PlayerRoll{{DieValues: "111234", Take: "111", Points: 300}},
+	}}, true, squelch.GameState{})
+
+	opts := squelch.Options("11256")
+	for i := range opts {
+		opts[i].ID = string(rune('a' + i))
+	}
+
+	// 9 is out of range, so we're asked again and take the second option
+	c, err := p.Choose("m", "1", "2", "11256", opts, squelch.GameState{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if c.TakeOptionID != "b" || c.Stay {
+		t.Errorf("Choice incorrect, got %+v", c)
+	}
+
+	c, err = p.Choose("m", "1", "2", "115", squelch.Options("115"), squelch.GameState{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !c.Stay {
+		t.Errorf("expected to stay, got %+v", c)
+	}
+
+	for _, want := range []string{"Scores: You 0 | Bot 300", "FINAL ROUND", "[1] [1] [2] [5] [6]", "pick an option from 1 to 5"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%v", want, out.String())
+		}
+	}
+
+	if _, err := p.Choose("m", "1", "2", "115", squelch.Options("115"), squelch.GameState{}); err == nil {
+		t.Errorf("expected an error once input runs out")
+	}
+}
@@ -0,0 +1,282 @@
+package localbot
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// builtins are the reference strategies by name, built from their
+// query-string parameters
+var builtins = map[string]func(params url.Values) (Strategy, error){
+	"threshold": func(params url.Values) (Strategy, error) {
+		s := &ThresholdStrategy{Threshold: 300}
+		return s, intParams(params, map[string]*int{"t": &s.Threshold})
+	},
+	"dice": func(params url.Values) (Strategy, error) {
+		s := &DiceAwareStrategy{Thresholds: [7]int{0, 100, 200, 300, 400, 600, 2000}}
+		return s, intParams(params, map[string]*int{
+			"t1": &s.Thresholds[1], "t2": &s.Thresholds[2], "t3": &s.Thresholds[3],
+			"t4": &s.Thresholds[4], "t5": &s.Thresholds[5], "t6": &s.Thresholds[6],
+		})
+	},
+	"catchup": func(params url.Values) (Strategy, error) {
+		s := &CatchUpStrategy{Threshold: 300, Behind: 1000, Extra: 400}
+		return s, intParams(params, map[string]*int{"t": &s.Threshold, "behind": &s.Behind, "extra": &s.Extra})
+	},
+	"maxev": func(params url.Values) (Strategy, error) {
+		return &MaxEVStrategy{}, intParams(params, nil)
+	},
+	"random": func(params url.Values) (Strategy, error) {
+		stay := 30
+		seed := 0
+		if err := intParams(params, map[string]*int{"stay": &stay, "seed": &seed}); err != nil {
+			return nil, err
+		}
+		return NewRandomStrategy(float64(stay)/100, int64(seed)), nil
+	},
+	"endgame": func(params url.Values) (Strategy, error) {
+		s := &EndgameStrategy{Threshold: 300, Near: 1000, Lead: 500}
+		return s, intParams(params, map[string]*int{"t": &s.Threshold, "near": &s.Near, "lead": &s.Lead})
+	},
+	"tuned": func(params url.Values) (Strategy, error) {
+		s := &TunedStrategy{Thresholds: [7]int{0, 100, 200, 300, 400, 600, 2000}, Near: 1000, Push: 300, Lead: 500}
+		return s, intParams(params, map[string]*int{
+			"t1": &s.Thresholds[1], "t2": &s.Thresholds[2], "t3": &s.Thresholds[3],
+			"t4": &s.Thresholds[4], "t5": &s.Thresholds[5], "t6": &s.Thresholds[6],
+			"near": &s.Near, "push": &s.Push, "lead": &s.Lead,
+		})
+	},
+}
+
+// BuiltinNames returns the names of the reference strategies
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for n := range builtins {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBuiltinPlayer makes a reference bot from a spec like "threshold?t=450".
+// The spec is used as the player name.
+func NewBuiltinPlayer(spec string) (*StrategyPlayer, error) {
+	name, query := spec, ""
+	if i := strings.Index(spec, "?"); i >= 0 {
+		name, query = spec[:i], spec[i+1:]
+	}
+
+	mk, ok := builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown builtin bot %q, choose from %v", name, BuiltinNames())
+	}
+
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("builtin bot %q: %v", spec, err)
+	}
+
+	s, err := mk(params)
+	if err != nil {
+		return nil, fmt.Errorf("builtin bot %q: %v", spec, err)
+	}
+
+	return NewStrategyPlayer(spec, s), nil
+}
+
+// intParams reads integer parameters into their targets, rejecting any
+// parameter that isn't known
+func intParams(params url.Values, targets map[string]*int) error {
+	for k, v := range params {
+		t, ok := targets[k]
+		if !ok {
+			return fmt.Errorf("unknown parameter %q", k)
+		}
+		n, err := strconv.Atoi(v[0])
+		if err != nil {
+			return fmt.Errorf("parameter %q: %v", k, err)
+		}
+		*t = n
+	}
+	return nil
+}
+
+// finalRound handles the final round for most strategies: take the most
+// points and keep rolling until we're past the leader
+func finalRound(state TurnState, options []squelch.ScoringOption) (int, bool) {
+	return 0, state.Score+state.TurnPoints+options[0].Points > state.HighScore
+}
+
+// ThresholdStrategy takes the highest option and stays once the turn is worth
+// at least Threshold points
+type ThresholdStrategy struct {
+	Threshold int
+}
+
+func (s *ThresholdStrategy) Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (int, bool) {
+	if state.IsFinalRound {
+		return finalRound(state, options)
+	}
+	return 0, state.TurnPoints+options[0].Points >= s.Threshold
+}
+
+// DiceAwareStrategy takes the highest option and stays once the turn is worth
+// at least the threshold for the number of dice it would roll next
+type DiceAwareStrategy struct {
+	// Thresholds is indexed by the dice left, 1-6
+	Thresholds [7]int
+}
+
+func (s *DiceAwareStrategy) Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (int, bool) {
+	if state.IsFinalRound {
+		return finalRound(state, options)
+	}
+
+	left := len(dieValues) - len(options[0].DieValues)
+	if left == 0 {
+		left = 6
+	}
+	return 0, state.TurnPoints+options[0].Points >= s.Thresholds[left]
+}
+
+// CatchUpStrategy plays like ThresholdStrategy but pushes for Extra points
+// per turn while it's at least Behind points behind the leader
+type CatchUpStrategy struct {
+	Threshold int
+	Behind    int
+	Extra     int
+}
+
+func (s *CatchUpStrategy) Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (int, bool) {
+	if state.IsFinalRound {
+		return finalRound(state, options)
+	}
+
+	t := s.Threshold
+	if state.HighScore-state.Score >= s.Behind {
+		t += s.Extra
+	}
+	return 0, state.TurnPoints+options[0].Points >= t
+}
+
+// MaxEVStrategy takes whichever option maximizes the expected points banked
+// this turn, and stays when rolling again would lower them
+type MaxEVStrategy struct{}
+
+func (s *MaxEVStrategy) Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (int, bool) {
+	if state.IsFinalRound {
+		return finalRound(state, options)
+	}
+
+	// the analysis is in the same order as the options
+	best := 0
+	evs, err := squelch.AnalyzeRoll(dieValues, state.TurnPoints)
+	if err != nil || len(evs) == 0 {
+		return 0, true
+	}
+	for i, ev := range evs {
+		if ev.EV > evs[best].EV {
+			best = i
+		}
+	}
+	return best, evs[best].Stay
+}
+
+// RandomStrategy takes a random option and stays with a fixed chance. It
+// makes a good floor for any other bot to beat.
+type RandomStrategy struct {
+	StayChance float64
+
+	sync sync.Mutex
+	rand *rand.Rand
+}
+
+// NewRandomStrategy makes a random strategy. A seed of 0 picks a random seed.
+func NewRandomStrategy(stayChance float64, seed int64) *RandomStrategy {
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	return &RandomStrategy{StayChance: stayChance, rand: rand.New(rand.NewSource(seed))}
+}
+
+func (s *RandomStrategy) Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (int, bool) {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+	return s.rand.Intn(len(options)), s.rand.Float64() < s.StayChance
+}
+
+// EndgameStrategy plays like ThresholdStrategy until someone gets within Near
+// points of the target. Then it won't end the regular rounds by reaching the
+// target without at least a Lead point cushion, and it pushes harder while
+// an opponent is close to the target.
+type EndgameStrategy struct {
+	Threshold int
+	Near      int
+	Lead      int
+}
+
+func (s *EndgameStrategy) Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (int, bool) {
+	if state.IsFinalRound {
+		return finalRound(state, options)
+	}
+
+	total := state.Score + state.TurnPoints + options[0].Points
+	turn := state.TurnPoints + options[0].Points
+
+	if state.TargetScore > 0 {
+		if total >= state.TargetScore {
+			// going over gives everybody one last turn, so make it hard to catch us
+			return 0, total-state.HighScore >= s.Lead
+		}
+
+		if state.TargetScore-state.HighScore <= s.Near {
+			// they could finish soon, a small turn won't save us
+			return 0, turn >= 2*s.Threshold
+		}
+	}
+
+	return 0, turn >= s.Threshold
+}
+
+// TunedStrategy combines the dice-aware thresholds with endgame play, with
+// every knob exposed for parameter tuning. While an opponent is within Near
+// points of the target it wants Push more points per turn, and it won't
+// reach the target without a Lead point cushion.
+type TunedStrategy struct {
+	// Thresholds is indexed by the dice left, 1-6
+	Thresholds [7]int
+	Near       int
+	Push       int
+	Lead       int
+}
+
+func (s *TunedStrategy) Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (int, bool) {
+	if state.IsFinalRound {
+		return finalRound(state, options)
+	}
+
+	turn := state.TurnPoints + options[0].Points
+	left := len(dieValues) - len(options[0].DieValues)
+	if left == 0 {
+		left = 6
+	}
+	t := s.Thresholds[left]
+
+	if state.TargetScore > 0 {
+		if state.Score+turn >= state.TargetScore {
+			return 0, state.Score+turn-state.HighScore >= s.Lead
+		}
+		if state.TargetScore-state.HighScore <= s.Near {
+			t += s.Push
+		}
+	}
+
+	return 0, turn >= t
+}
@@ -0,0 +1,68 @@
+package localbot
+
+import (
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestNewBuiltinPlayer(t *testing.T) {
+	for _, n := range BuiltinNames() {
+		if _, err := NewBuiltinPlayer(n); err != nil {
+			t.Errorf("%v: %v", n, err)
+		}
+	}
+
+	p, err := NewBuiltinPlayer("threshold?t=450")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := 450, p.strategy.(*ThresholdStrategy).Threshold; want != got {
+		t.Errorf("Threshold incorrect, want %v got %v", want, got)
+	}
+
+	for _, spec := range []string{"nope", "threshold?x=1", "threshold?t=high"} {
+		if _, err := NewBuiltinPlayer(spec); err == nil {
+			t.Errorf("%v: expected an error", spec)
+		}
+	}
+}
+
+func TestStrategyPlayer_Threshold(t *testing.T) {
+	p, _ := NewBuiltinPlayer("threshold?t=200")
+	opts := squelch.Options("12346")
+	for i := range opts {
+		opts[i].ID = string(rune('0' + i))
+	}
+
+	state := squelch.GameState{Scores: []int{0, 0}, TargetScore: 5000, OvertimeBotIndex: -1}
+	if c, _ := p.Choose("m", "g", "1", "12346", opts, state); c.Stay {
+		t.Errorf("expected to keep rolling at 100 points")
+	}
+	state.TurnPoints = 100
+	if c, _ := p.Choose("m", "g", "1", "12346", opts, state); !c.Stay {
+		t.Errorf("expected to stay at 200 points")
+	}
+
+	// in the final round only passing the leader matters
+	state = squelch.GameState{Scores: []int{1000, 5000}, TargetScore: 5000, TurnPoints: 100, IsFinalRound: true, OvertimeBotIndex: 1}
+	if c, _ := p.Choose("m", "g", "2", "12346", opts, state); c.Stay {
+		t.Errorf("expected to keep rolling behind the leader")
+	}
+}
+
+func TestMaxEVStrategy(t *testing.T) {
+	s := &MaxEVStrategy{}
+
+	// with 300 at risk it's better to take all three scoring dice and stay
+	idx, stay := s.Choose(TurnState{TurnPoints: 300}, "11256", squelch.Options("11256"))
+	if idx != 0 || !stay {
+		t.Errorf("Choice incorrect, got %v %v", idx, stay)
+	}
+
+	// with nothing at risk, set aside a single 1 and roll four dice
+	idx, stay = s.Choose(TurnState{}, "12346", squelch.Options("12346"))
+	if idx != 0 || stay {
+		t.Errorf("Choice incorrect, got %v %v", idx, stay)
+	}
+}
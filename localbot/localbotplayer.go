@@ -24,10 +24,10 @@ func NewLocalBotPlayer(name string) *LocalBotPlayer {
 }
 
 func (p *LocalBotPlayer) Info() (*squelch.PlayerInfo, error) {
-	return &squelch.PlayerInfo{p.name}, nil
+	return &squelch.PlayerInfo{Name: p.name}, nil
 }
 
-func (p *LocalBotPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string) error {
+func (p *LocalBotPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
 	return nil
 }
 
@@ -43,7 +43,7 @@ func (p *LocalBotPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []sque
 	return nil
 }
 
-func (p *LocalBotPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool) error {
+func (p *LocalBotPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, game squelch.GameState) error {
 	state := p.getState(matchID, gameID, turnID)
 	state.isFinalRound = isFinalRound
 
@@ -56,7 +56,7 @@ func (p *LocalBotPlayer) TurnStart(matchID, gameID, turnID string, startPoints i
 	return nil
 }
 
-func (p *LocalBotPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption) (*squelch.PlayerChoice, error) {
+func (p *LocalBotPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, game squelch.GameState) (*squelch.PlayerChoice, error) {
 	// choose the highest scoring option every time, stay if > turn total 300 points
 	// get turn metadata
 	state := p.getState(matchID, gameID, turnID)
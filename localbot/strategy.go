@@ -0,0 +1,90 @@
+package localbot
+
+import (
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+var _ squelch.Player = &StrategyPlayer{}
+
+// Strategy decides what to do with a single roll. It returns the index of the
+// option to take and if the player should stay afterwards.
+type Strategy interface {
+	Choose(state TurnState, dieValues string, options []squelch.ScoringOption) (optionIdx int, stay bool)
+}
+
+// TurnState is everything a StrategyPlayer knows about the game when it has
+// to make a choice.
+type TurnState struct {
+	// Score is the player's score at the start of the turn, their team's in
+	// a team game
+	Score int
+	// TurnPoints are the points taken so far this turn
+	TurnPoints int
+	// HighScore is the highest score of the other players, or other teams
+	HighScore int
+	// TargetScore is the score that triggers the final round
+	TargetScore  int
+	IsFinalRound bool
+}
+
+// StrategyPlayer is a local bot that plays a Strategy. It needs nothing but
+// the game state it's handed, so one player can be in any number of games.
+type StrategyPlayer struct {
+	strategy Strategy
+	name     string
+}
+
+// NewStrategyPlayer makes a local bot that plays the given strategy
+func NewStrategyPlayer(name string, s Strategy) *StrategyPlayer {
+	return &StrategyPlayer{
+		strategy: s,
+		name:     name,
+	}
+}
+
+func (p *StrategyPlayer) Info() (*squelch.PlayerInfo, error) {
+	return &squelch.PlayerInfo{Name: p.name}, nil
+}
+
+func (p *StrategyPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
+	return nil
+}
+
+func (p *StrategyPlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	return nil
+}
+
+func (p *StrategyPlayer) GameStart(matchID, gameID string) error {
+	return nil
+}
+
+func (p *StrategyPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	return nil
+}
+
+func (p *StrategyPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, state squelch.GameState) error {
+	return nil
+}
+
+func (p *StrategyPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, state squelch.GameState) (*squelch.PlayerChoice, error) {
+	idx, stay := p.strategy.Choose(TurnState{
+		Score:        state.TeamScore(),
+		TurnPoints:   state.TurnPoints,
+		HighScore:    state.HighScore(),
+		TargetScore:  state.Target(),
+		IsFinalRound: state.IsFinalRound,
+	}, dieValues, options)
+
+	if idx < 0 || idx >= len(options) {
+		idx = 0
+	}
+
+	return &squelch.PlayerChoice{
+		TakeOptionID: options[idx].ID,
+		Stay:         stay,
+	}, nil
+}
+
+func (p *StrategyPlayer) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	return nil
+}
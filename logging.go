@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// logFlags are the engine logging flags shared by every command
+type logFlags struct {
+	level  *string
+	format *string
+}
+
+func newLogFlags(fs *flag.FlagSet) logFlags {
+	return logFlags{
+		level:  fs.String("log-level", "off", "engine log level: debug, info, warn, error or off"),
+		format: fs.String("log-format", "text", "engine log format: text or json"),
+	}
+}
+
+// setup points the engine logger at stderr, stdout is left for our output
+func (f logFlags) setup() error {
+	if strings.EqualFold(*f.level, "off") {
+		squelch.SetLogger(nil)
+		return nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*f.level)); err != nil {
+		return fmt.Errorf("invalid log level %q", *f.level)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(*f.format) {
+	case "text":
+		squelch.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, opts)))
+	case "json":
+		squelch.SetLogger(slog.New(slog.NewJSONHandler(os.Stderr, opts)))
+	default:
+		return fmt.Errorf("invalid log format %q, use text or json", *f.format)
+	}
+	return nil
+}
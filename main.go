@@ -1,98 +1,104 @@
 package main
 
 import (
-	"errors"
-	"flag"
 	"fmt"
-	"log"
-	"math/rand"
-	"time"
-
-	"github.com/dlclark/squelchbot-arena-go/localbot"
-	"github.com/dlclark/squelchbot-arena-go/squelch"
-)
-
-// our flags
-var (
-	//+urls
-	gpm = flag.Int("gpm", 10, "games per match")
-	ppm = flag.Int("ppm", -1, "players per match")
+	"os"
+	"path/filepath"
+	"strings"
 )
 
-func main() {
-	var us urls
-	flag.Var(&us, "url", "a client bot URL. Repeatable.")
-	flag.Parse()
-
-	// set related defaults
-	if *ppm == -1 {
-		*ppm = len(us)
-	}
+// command is one of our subcommands
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
 
-	// validate our inputs
-	if err := validateInputs(us, *gpm, *ppm); err != nil {
-		log.Fatalf("Invalid input: %v", err)
+// commands are listed in the help in this order
+var commands []command
+
+func init() {
+	// set here because help lists the commands
+	commands = []command{
+		{"run", "play a tournament between bots and rank them", runTournament},
+		{"play", "play a match against bots at the terminal", runPlay},
+		{"analyze", "show the odds and expected value of a roll", runAnalyze},
+		{"replay", "replay the games in a recorded event log", runReplay},
+		{"validate", "check bots against the conformance scenarios", runValidate},
+		{"serve", "serve the web UI to play bots and watch them play", runServe},
+		{"worker", "play matches for a distributed tournament", runWorker},
+		{"history", "list the tournaments in the results file", runHistory},
+		{"leaderboard", "rank every bot over the tournaments in the results file", runLeaderboard},
+		{"show", "show a tournament from the results file", runShow},
+		{"optimize", "tune a reference strategy's parameters", runOptimize},
+		{"gym", "serve a reinforcement learning environment over stdio", runGym},
+		{"help", "show the help for a command", runHelp},
 	}
+}
 
-	// setup our match
-	// start running games based on number of configured concurrent count
-	p := make([]squelch.Player, len(us))
+// aliases are the older names of commands
+var aliases = map[string]string{
+	"conformance": "validate",
+	"web":         "serve",
+}
 
-	/*for i, u := range us {
-		p[i] = squelch.NewApiPlayer(u)
-	}*/
-	p = []squelch.Player{
-		localbot.NewLocalBotPlayer("Local1"),
-		localbot.NewLocalBotPlayer("Local2"),
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
 	}
 
-	rand.Seed(time.Now().UTC().UnixNano())
-
-	t := squelch.NewTournament(*gpm, *ppm, 5000, p)
-
-	// number of matches to have an even tournament
-	mc := t.GetMatchCount()
-
-	// total game count
-	totalGc := mc * *gpm
-
-	// print our summary line
-	fmt.Printf("Starting tournament with %v entrants, %v players per match, %v matches totaling %v games.\n", len(p), *ppm, mc, totalGc)
-
-	// start the tournament!
-	r, err := t.Run()
-	if err != nil {
-		fmt.Printf("An error running the tournament: %v\n", err)
+	name, args := os.Args[1], os.Args[2:]
+	if strings.HasPrefix(name, "-") && name != "-h" && name != "-help" && name != "--help" {
+		// flags without a command run a tournament, like before there were
+		// commands
+		name, args = "run", os.Args[1:]
 	}
 
-	if r == nil {
+	if c, ok := findCommand(name); ok {
+		c.run(args)
 		return
 	}
 
-	// output final points
-	fmt.Println("Ranks:")
-	for i, s := range r.Points {
-		fmt.Printf("\t%v: %v (won %4.1f%% match, %4.1f%% game)\n", i+1, r.EntrantNames[s.EntrantIndex],
-			float64(s.Points*100.0)/float64(s.Matches), float64(s.TotalWins*100.0)/float64(s.Matches**gpm))
+	if name != "-h" && name != "-help" && name != "--help" {
+		fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n", name)
 	}
+	usage()
+	os.Exit(2)
 }
 
-func validateInputs(us urls, gpm, ppm int) error {
-	if len(us) < 2 {
-		return errors.New("at least 2 URLs are required")
+func findCommand(name string) (command, bool) {
+	if a, ok := aliases[name]; ok {
+		name = a
 	}
-
-	if gpm < 1 {
-		return errors.New("at least 1 game per match is required")
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
 	}
+	return command{}, false
+}
 
-	if ppm < 2 {
-		return errors.New("at least 2 players per match are required")
+func usage() {
+	prog := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %v <command> [flags]\n\nCommands:\n", prog)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-12v %v\n", c.name, c.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun '%v help <command>' for a command's flags.\n", prog)
+}
 
-	if ppm > len(us) {
-		return fmt.Errorf("players per match cannot exceed provided URL count (%v)", len(us))
+// runHelp shows a command's flags
+func runHelp(args []string) {
+	if len(args) != 1 {
+		usage()
+		return
 	}
 
-	return nil
+	c, ok := findCommand(args[0])
+	if !ok || c.name == "help" {
+		usage()
+		return
+	}
+	c.run([]string{"-h"})
 }
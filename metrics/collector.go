@@ -0,0 +1,144 @@
+// Package metrics exposes tournament progress in the Prometheus text format,
+// so long tournaments can be charted while they run.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+var (
+	_ squelch.Observer         = &Collector{}
+	_ squelch.CallbackObserver = &Collector{}
+)
+
+// latencyBuckets are the callback latency histogram buckets in seconds
+var latencyBuckets = []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// turnBuckets are the turns per game histogram buckets
+var turnBuckets = []float64{5, 10, 15, 20, 25, 30, 40, 50, 75, 100}
+
+// Collector is a tournament observer that counts what happens and serves it
+// on /metrics. Add it to a Tournament with AddObserver.
+type Collector struct {
+	sync sync.Mutex
+
+	matchesInFlight  *counterVec
+	matchesCompleted *counterVec
+	gamesCompleted   *counterVec
+	gameErrors       *counterVec
+	turns            *counterVec
+	squelches        *counterVec
+	rollovers        *counterVec
+	gameTurns        *histogramVec
+	callbackErrors   *counterVec
+	callbackLatency  *histogramVec
+
+	// turnCounts are the turns so far of the games in progress
+	turnCounts map[string]int
+}
+
+// NewCollector makes an empty collector
+func NewCollector() *Collector {
+	return &Collector{
+		matchesInFlight:  newCounterVec("gauge", "squelch_matches_in_flight", "Matches being played right now."),
+		matchesCompleted: newCounterVec("counter", "squelch_matches_completed_total", "Matches played to the end."),
+		gamesCompleted:   newCounterVec("counter", "squelch_games_completed_total", "Games played to the end, with or without an error."),
+		gameErrors:       newCounterVec("counter", "squelch_game_errors_total", "Games ended by a bot error, by bot.", "bot"),
+		turns:            newCounterVec("counter", "squelch_turns_total", "Turns taken, by bot.", "bot"),
+		squelches:        newCounterVec("counter", "squelch_squelches_total", "Turns ended by a squelch, by bot.", "bot"),
+		rollovers:        newCounterVec("counter", "squelch_rollovers_total", "Rollovers, by bot.", "bot"),
+		gameTurns:        newHistogramVec("squelch_game_turns", "Turns per completed game.", turnBuckets),
+		callbackErrors:   newCounterVec("counter", "squelch_bot_callback_errors_total", "Bot callbacks that returned an error, by bot and callback.", "bot", "callback"),
+		callbackLatency:  newHistogramVec("squelch_bot_callback_seconds", "Bot callback latency, by bot and callback.", latencyBuckets, "bot", "callback"),
+		turnCounts:       make(map[string]int),
+	}
+}
+
+func (c *Collector) OnMatchStart(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.matchesInFlight.add(1)
+}
+
+func (c *Collector) OnMatchEnd(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.matchesInFlight.add(-1)
+	c.matchesCompleted.add(1)
+}
+
+func (c *Collector) OnGameStart(e squelch.GameEvent) {}
+
+func (c *Collector) OnTurnStart(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.turns.add(1, e.Players[e.BotIndex])
+	c.turnCounts[e.MatchID+"/"+e.GameID]++
+}
+
+func (c *Collector) OnRoll(e squelch.GameEvent) {}
+
+func (c *Collector) OnChoice(e squelch.GameEvent) {}
+
+func (c *Collector) OnSquelch(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.squelches.add(1, e.Players[e.BotIndex])
+}
+
+func (c *Collector) OnRollover(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.rollovers.add(1, e.Players[e.BotIndex])
+}
+
+func (c *Collector) OnReroll(e squelch.GameEvent) {}
+
+func (c *Collector) OnOvertime(e squelch.GameEvent) {}
+
+func (c *Collector) OnGameEnd(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	key := e.MatchID + "/" + e.GameID
+	c.gamesCompleted.add(1)
+	c.gameTurns.observe(float64(c.turnCounts[key]))
+	delete(c.turnCounts, key)
+
+	// a game stopped by the tournament isn't any bot's error
+	if e.Err != "" && e.BotIndex >= 0 {
+		c.gameErrors.add(1, e.Players[e.BotIndex])
+	}
+}
+
+func (c *Collector) OnCallback(botName, callback string, d time.Duration, err error) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	c.callbackLatency.observe(d.Seconds(), botName, callback)
+	if err != nil {
+		c.callbackErrors.add(1, botName, callback)
+	}
+}
+
+// ServeHTTP writes every metric in the Prometheus text format
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.matchesInFlight.write(w)
+	c.matchesCompleted.write(w)
+	c.gamesCompleted.write(w)
+	c.gameErrors.write(w)
+	c.turns.write(w)
+	c.squelches.write(w)
+	c.rollovers.write(w)
+	c.gameTurns.write(w)
+	c.callbackErrors.write(w)
+	c.callbackLatency.write(w)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestCollector(t *testing.T) {
+	a, _ := localbot.NewBuiltinPlayer("threshold")
+	b, _ := localbot.NewBuiltinPlayer("maxev")
+
+	c := NewCollector()
+	tr := squelch.NewTournament(3, 2, 1000, []squelch.Player{a, b})
+	tr.AddObserver(c)
+	if _, err := tr.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE squelch_matches_in_flight gauge\nsquelch_matches_in_flight 0\n",
+		"squelch_matches_completed_total 1\n",
+		"squelch_games_completed_total 3\n",
+		"squelch_game_turns_count 3\n",
+		`squelch_turns_total{bot="threshold"}`,
+		`squelch_bot_callback_seconds_bucket{bot="maxev",callback="Choose",le="+Inf"}`,
+		"# TYPE squelch_bot_callback_seconds histogram\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q:\n%v", want, out)
+		}
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	got := formatLabels([]string{"bot", "callback"}, labels{`say "hi"\` + "\n", "Choose"})
+	if want := `{bot="say \"hi\"\\\n",callback="Choose"}`; got != want {
+		t.Errorf("want %v got %v", want, got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogramVec("h", "help", []float64{1, 5})
+	h.observe(0.5)
+	h.observe(3)
+	h.observe(10)
+
+	sb := &strings.Builder{}
+	h.write(sb)
+	for _, want := range []string{`h_bucket{le="1"} 1`, `h_bucket{le="5"} 2`, `h_bucket{le="+Inf"} 3`, "h_sum 13.5", "h_count 3"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("histogram missing %q:\n%v", want, sb.String())
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// labels are metric label values in the order of the metric's label names
+type labels []string
+
+func (l labels) key() string {
+	return strings.Join(l, "\xff")
+}
+
+// counterVec is a counter or gauge by label values
+type counterVec struct {
+	name, help, kind string
+	labelNames       []string
+	values           map[string]float64
+	labels           map[string]labels
+}
+
+func newCounterVec(kind, name, help string, labelNames ...string) *counterVec {
+	return &counterVec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+		labels:     make(map[string]labels),
+	}
+}
+
+func (c *counterVec) add(v float64, lv ...string) {
+	k := labels(lv).key()
+	c.values[k] += v
+	c.labels[k] = lv
+}
+
+func (c *counterVec) write(w io.Writer) {
+	writeHeader(w, c.name, c.help, c.kind)
+	if len(c.labelNames) == 0 && len(c.values) == 0 {
+		// unlabeled metrics always show up, even at 0
+		fmt.Fprintf(w, "%v 0\n", c.name)
+		return
+	}
+	for _, k := range sortedKeys(c.labels) {
+		fmt.Fprintf(w, "%v%v %v\n", c.name, formatLabels(c.labelNames, c.labels[k]), formatFloat(c.values[k]))
+	}
+}
+
+// histogramVec is a histogram by label values
+type histogramVec struct {
+	name, help string
+	labelNames []string
+	buckets    []float64
+	hists      map[string]*histogram
+	labels     map[string]labels
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labelNames ...string) *histogramVec {
+	return &histogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		hists:      make(map[string]*histogram),
+		labels:     make(map[string]labels),
+	}
+}
+
+func (h *histogramVec) observe(v float64, lv ...string) {
+	k := labels(lv).key()
+	hist, ok := h.hists[k]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.hists[k] = hist
+		h.labels[k] = lv
+	}
+
+	for i, b := range h.buckets {
+		if v <= b {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, k := range sortedKeys(h.labels) {
+		hist, lv := h.hists[k], h.labels[k]
+		names := append(append([]string(nil), h.labelNames...), "le")
+
+		for i, b := range h.buckets {
+			le := append(append(labels(nil), lv...), formatFloat(b))
+			fmt.Fprintf(w, "%v_bucket%v %v\n", h.name, formatLabels(names, le), hist.counts[i])
+		}
+		inf := append(append(labels(nil), lv...), "+Inf")
+		fmt.Fprintf(w, "%v_bucket%v %v\n", h.name, formatLabels(names, inf), hist.count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", h.name, formatLabels(h.labelNames, lv), formatFloat(hist.sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", h.name, formatLabels(h.labelNames, lv), hist.count)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %v %v\n", name, help)
+	fmt.Fprintf(w, "# TYPE %v %v\n", name, kind)
+}
+
+func formatLabels(names []string, values labels) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = fmt.Sprintf("%v=\"%v\"", n, escape(values[i]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// escape makes a label value safe for the text format
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+func sortedKeys(m map[string]labels) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/optimize"
+)
+
+// runOptimize evolves the tuned bot's parameters against a pool of opponents
+func runOptimize(args []string) {
+	fs := newFlagSet("optimize", "[flags]", "Evolve a reference strategy's parameters by playing candidates against the -vs bots.")
+	vs := addVsFlag(fs, "builtin:threshold and builtin:maxev")
+	pop := fs.Int("pop", 20, "population size")
+	gens := fs.Int("gens", 10, "number of generations")
+	par := fs.Int("par", runtime.NumCPU(), "candidates evaluated in parallel")
+	games := fs.Int("games", 50, "games each candidate plays against each opponent")
+	target := fs.Int("target", 5000, "target score")
+	mutation := fs.Float64("mutation", 0.2, "chance each parameter mutates")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed for the optimizer and its games")
+	lf := newLogFlags(fs)
+	fs.Parse(args)
+
+	if err := lf.setup(); err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	if len(*vs) == 0 {
+		*vs = specs{"builtin:threshold", "builtin:maxev"}
+	}
+
+	opponents, err := makeBots(*vs, "")
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	fmt.Printf("Optimizing against %v with population %v for %v generations\n", vs, *pop, *gens)
+
+	best, err := optimize.Run(optimize.Config{
+		Population:    *pop,
+		Generations:   *gens,
+		Parallelism:   *par,
+		GamesPerMatch: *games,
+		TargetScore:   *target,
+		Opponents:     opponents,
+		MutationRate:  *mutation,
+		Seed:          *seed,
+		Progress: func(generation int, best optimize.Candidate) {
+			fmt.Printf("\tgeneration %v: best %4.1f%% %v\n", generation, best.Fitness*100, best.Params.Spec())
+		},
+	})
+	if err != nil {
+		fmt.Printf("An error optimizing: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Best (won %4.1f%% of games):\n\tbuiltin:%v\n", best.Fitness*100, best.Params.Spec())
+}
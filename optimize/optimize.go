@@ -0,0 +1,258 @@
+// Package optimize tunes the parameters of the "tuned" built-in bot with a
+// genetic algorithm, scoring each candidate by its win rate in headless
+// tournaments against a fixed pool of opponents.
+package optimize
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// Gene is a single tunable parameter of the tuned bot
+type Gene struct {
+	Name     string
+	Min, Max int
+}
+
+// Genes are the parameters being evolved, in Params order
+var Genes = []Gene{
+	{"t1", 0, 2000},
+	{"t2", 0, 2000},
+	{"t3", 0, 2000},
+	{"t4", 0, 2000},
+	{"t5", 0, 2000},
+	{"t6", 0, 3000},
+	{"near", 0, 3000},
+	{"push", 0, 2000},
+	{"lead", 0, 2000},
+}
+
+// step is the granularity of every gene; all points are multiples of 50
+const step = 50
+
+// Params are values for each of the Genes
+type Params []int
+
+// Spec is the built-in bot spec for the params, e.g. tuned?t1=100&...
+func (p Params) Spec() string {
+	kv := make([]string, len(p))
+	for i, v := range p {
+		kv[i] = fmt.Sprintf("%v=%v", Genes[i].Name, v)
+	}
+	return "tuned?" + strings.Join(kv, "&")
+}
+
+// Config controls an optimization run
+type Config struct {
+	Population  int
+	Generations int
+	// Parallelism is how many candidates are evaluated at once
+	Parallelism int
+	// GamesPerMatch is how many games each candidate plays each opponent
+	GamesPerMatch int
+	TargetScore   int
+	// Opponents is the fixed pool every candidate is scored against
+	Opponents []squelch.Player
+	// MutationRate is the chance each gene mutates in a child
+	MutationRate float64
+	// Seed makes the run repeatable, both the evolution and the dice of
+	// every fitness game
+	Seed int64
+
+	// Progress, if set, is called after every generation with the best
+	// candidate so far
+	Progress func(generation int, best Candidate)
+}
+
+// Candidate is a parameter set and its fitness
+type Candidate struct {
+	Params Params
+	// Fitness is the candidate's win rate against the opponent pool
+	Fitness float64
+}
+
+// Run evolves the tuned bot parameters and returns the best candidate found
+func Run(cfg Config) (Candidate, error) {
+	if cfg.Population < 2 {
+		return Candidate{}, errors.New("population must be at least 2")
+	}
+	if cfg.Generations < 1 {
+		return Candidate{}, errors.New("at least 1 generation is required")
+	}
+	if cfg.GamesPerMatch < 1 {
+		return Candidate{}, errors.New("at least 1 game per match is required")
+	}
+	if cfg.TargetScore < 1 {
+		return Candidate{}, errors.New("target score must be at least 1")
+	}
+	if len(cfg.Opponents) == 0 {
+		return Candidate{}, errors.New("at least 1 opponent is required")
+	}
+	if cfg.Parallelism < 1 {
+		cfg.Parallelism = 1
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	pop := make([]Candidate, cfg.Population)
+	for i := range pop {
+		pop[i].Params = randomParams(rng)
+	}
+
+	var best Candidate
+	for gen := 1; gen <= cfg.Generations; gen++ {
+		if err := evaluate(cfg, rng, pop); err != nil {
+			return Candidate{}, err
+		}
+
+		sort.SliceStable(pop, func(i, j int) bool {
+			return pop[i].Fitness > pop[j].Fitness
+		})
+		if gen == 1 || pop[0].Fitness > best.Fitness {
+			best = Candidate{append(Params(nil), pop[0].Params...), pop[0].Fitness}
+		}
+		if cfg.Progress != nil {
+			cfg.Progress(gen, best)
+		}
+		if gen == cfg.Generations {
+			break
+		}
+
+		pop = nextGeneration(cfg, rng, pop)
+	}
+
+	return best, nil
+}
+
+// evaluate scores every candidate in parallel. Each gets its own dice seed
+// from rng so the scores don't depend on which finishes first.
+func evaluate(cfg Config, rng *rand.Rand, pop []Candidate) error {
+	seeds := make([]int64, len(pop))
+	for i := range seeds {
+		seeds[i] = rng.Int63()
+	}
+
+	work := make(chan int)
+	errs := make(chan error, len(pop))
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < cfg.Parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				f, err := Fitness(cfg, pop[i].Params, seeds[i])
+				if err != nil {
+					errs <- err
+					continue
+				}
+				pop[i].Fitness = f
+			}
+		}()
+	}
+
+	for i := range pop {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+// Fitness plays the params against every opponent and returns its game win
+// rate. The dice and seating come from seed, so the same seed gives the same
+// fitness.
+func Fitness(cfg Config, params Params, seed int64) (float64, error) {
+	bot, err := localbot.NewBuiltinPlayer(params.Spec())
+	if err != nil {
+		return 0, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	wins, games := 0, 0
+	for _, o := range cfg.Opponents {
+		t := squelch.NewTournament(cfg.GamesPerMatch, 2, cfg.TargetScore, []squelch.Player{bot, o})
+		t.SetRand(rng)
+		r, err := t.Run()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, p := range r.Points {
+			if p.EntrantIndex == 0 {
+				wins += p.TotalWins
+			}
+		}
+		games += cfg.GamesPerMatch
+	}
+
+	return float64(wins) / float64(games), nil
+}
+
+// nextGeneration keeps the two best candidates and breeds the rest
+func nextGeneration(cfg Config, rng *rand.Rand, pop []Candidate) []Candidate {
+	next := make([]Candidate, 0, len(pop))
+	for i := 0; i < 2 && i < len(pop); i++ {
+		next = append(next, Candidate{Params: pop[i].Params})
+	}
+
+	for len(next) < len(pop) {
+		a, b := selectParent(rng, pop), selectParent(rng, pop)
+		child := make(Params, len(Genes))
+		for g := range child {
+			// uniform crossover
+			child[g] = a.Params[g]
+			if rng.Intn(2) == 0 {
+				child[g] = b.Params[g]
+			}
+
+			if rng.Float64() < cfg.MutationRate {
+				// nudge by up to 10% of the gene's range
+				span := (Genes[g].Max - Genes[g].Min) / 10
+				child[g] = clamp(g, child[g]+rng.Intn(2*span+1)-span)
+			}
+		}
+		next = append(next, Candidate{Params: child})
+	}
+
+	return next
+}
+
+// selectParent picks the best of three random candidates
+func selectParent(rng *rand.Rand, pop []Candidate) Candidate {
+	best := pop[rng.Intn(len(pop))]
+	for i := 0; i < 2; i++ {
+		if c := pop[rng.Intn(len(pop))]; c.Fitness > best.Fitness {
+			best = c
+		}
+	}
+	return best
+}
+
+func randomParams(rng *rand.Rand) Params {
+	p := make(Params, len(Genes))
+	for g := range p {
+		p[g] = clamp(g, Genes[g].Min+rng.Intn(Genes[g].Max-Genes[g].Min+1))
+	}
+	return p
+}
+
+// clamp keeps a gene in range and on a multiple of step
+func clamp(g, v int) int {
+	if v < Genes[g].Min {
+		v = Genes[g].Min
+	}
+	if v > Genes[g].Max {
+		v = Genes[g].Max
+	}
+	return v / step * step
+}
@@ -0,0 +1,100 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestParams_Spec(t *testing.T) {
+	p := Params{100, 200, 300, 400, 500, 600, 700, 800, 900}
+	if want, got := "tuned?t1=100&t2=200&t3=300&t4=400&t5=500&t6=600&near=700&push=800&lead=900", p.Spec(); want != got {
+		t.Errorf("Spec incorrect, want %v got %v", want, got)
+	}
+	if _, err := localbot.NewBuiltinPlayer(p.Spec()); err != nil {
+		t.Errorf("Spec should make a builtin bot: %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	opp, _ := localbot.NewBuiltinPlayer("threshold")
+	gens := 0
+
+	best, err := Run(Config{
+		Population:    4,
+		Generations:   2,
+		Parallelism:   2,
+		GamesPerMatch: 4,
+		TargetScore:   1000,
+		Opponents:     []squelch.Player{opp},
+		MutationRate:  0.2,
+		Seed:          1,
+		Progress: func(generation int, best Candidate) {
+			gens++
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if want, got := 2, gens; want != got {
+		t.Errorf("Generations incorrect, want %v got %v", want, got)
+	}
+	if want, got := len(Genes), len(best.Params); want != got {
+		t.Errorf("Params incorrect, want %v got %v", want, got)
+	}
+	for g, v := range best.Params {
+		if v < Genes[g].Min || v > Genes[g].Max || v%step != 0 {
+			t.Errorf("gene %v out of range: %v", Genes[g].Name, v)
+		}
+	}
+	if best.Fitness < 0 || best.Fitness > 1 {
+		t.Errorf("Fitness out of range: %v", best.Fitness)
+	}
+}
+
+func TestRun_NoOpponents(t *testing.T) {
+	if _, err := Run(Config{Population: 2, Generations: 1, GamesPerMatch: 1, TargetScore: 1000}); err == nil {
+		t.Errorf("expected an error without opponents")
+	}
+}
+
+func TestRun_Invalid(t *testing.T) {
+	a, _ := localbot.NewBuiltinPlayer("threshold")
+	for _, cfg := range []Config{
+		{Population: 2, Generations: 1, GamesPerMatch: 0, TargetScore: 1000},
+		{Population: 2, Generations: 1, GamesPerMatch: 2, TargetScore: 0},
+	} {
+		cfg.Opponents = []squelch.Player{a}
+		if _, err := Run(cfg); err == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+	}
+}
+
+func TestRun_Seed(t *testing.T) {
+	run := func() Candidate {
+		a, _ := localbot.NewBuiltinPlayer("threshold")
+		b, _ := localbot.NewBuiltinPlayer("maxev")
+		best, err := Run(Config{
+			Population:    4,
+			Generations:   2,
+			Parallelism:   4,
+			GamesPerMatch: 10,
+			TargetScore:   1000,
+			Opponents:     []squelch.Player{a, b},
+			MutationRate:  0.2,
+			Seed:          7,
+		})
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		return best
+	}
+
+	first, second := run(), run()
+	if first.Fitness != second.Fitness || first.Params.Spec() != second.Params.Spec() {
+		t.Errorf("The same seed should give the same result, got %+v and %+v", first, second)
+	}
+}
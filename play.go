@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/dlclark/squelchbot-arena-go/humanbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// runPlay plays a match at the terminal against one or more bots
+func runPlay(args []string) {
+	fs := newFlagSet("play", "[flags]", "Play a match against the -vs bots at the terminal.")
+	vs := addVsFlag(fs, "builtin:threshold")
+	gpm := fs.Int("gpm", 1, "games to play")
+	target := fs.Int("target", 5000, "target score")
+	name := fs.String("name", "Human", "your player name")
+	lf := newLogFlags(fs)
+	fs.Parse(args)
+
+	if err := lf.setup(); err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	if len(*vs) == 0 {
+		*vs = specs{"builtin:threshold"}
+	}
+	if *gpm < 1 {
+		log.Fatalf("Invalid input: at least 1 game is required")
+	}
+
+	opponents, err := makeBots(*vs, "")
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	players := append([]squelch.Player{humanbot.NewHumanPlayer(*name, os.Stdin, os.Stdout)}, opponents...)
+	t := squelch.NewTournament(*gpm, len(players), *target, players)
+	if _, err := t.Run(); err != nil {
+		log.Fatalf("Error playing: %v", err)
+	}
+}
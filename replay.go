@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// runReplay prints the games in a -record file play by play
+func runReplay(args []string) {
+	fs := newFlagSet("replay", "[flags] <record file>", "Print the matches in a file written by run -record play by play.")
+	match := fs.String("match", "", "only the match with this id, a prefix will do")
+	game := fs.String("game", "", "only the game with this id, a prefix will do")
+	delay := fs.Duration("delay", 0, "pause after each roll and choice, e.g. 500ms to watch it play out")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+	defer f.Close()
+
+	events, err := squelch.ReadEvents(f)
+	if err != nil {
+		log.Fatalf("Error reading %v: %v", fs.Arg(0), err)
+	}
+
+	shown := 0
+	for _, e := range events {
+		if !strings.HasPrefix(e.MatchID, *match) {
+			continue
+		}
+		// match events have no game, keep them unless we want a single game
+		if *game != "" && !strings.HasPrefix(e.GameID, *game) {
+			continue
+		}
+
+		if line := describeEvent(e); line != "" {
+			fmt.Println(line)
+			shown++
+		}
+		if *delay > 0 && (e.Type == squelch.EventRoll || e.Type == squelch.EventChoice) {
+			time.Sleep(*delay)
+		}
+	}
+
+	if shown == 0 {
+		log.Fatalf("No events matched")
+	}
+}
+
+// describeEvent is a line of play by play for the event
+func describeEvent(e squelch.GameEvent) string {
+	name := func(i int) string {
+		if i >= 0 && i < len(e.Players) && e.Players[i] != "" {
+			return e.Players[i]
+		}
+		return fmt.Sprintf("bot %v", i)
+	}
+
+	switch e.Type {
+	case squelch.EventMatchStart:
+		return fmt.Sprintf("Match %v: %v", e.MatchID, strings.Join(e.Players, " vs "))
+	case squelch.EventGameStart:
+		return fmt.Sprintf("\tGame %v", e.GameID)
+	case squelch.EventTurnStart:
+		final := ""
+		if e.IsFinalRound {
+			final = ", final round"
+		}
+		return fmt.Sprintf("\t\t%v's turn, scores %v%v", name(e.BotIndex), scoreList(e), final)
+	case squelch.EventRoll:
+		return fmt.Sprintf("\t\t\trolled %v", e.Dice)
+	case squelch.EventChoice:
+		act := "rolls again"
+		if e.Stay {
+			act = "stays"
+		}
+		return fmt.Sprintf("\t\t\ttakes %v for %v, %v with %v", e.Take, e.Points, act, e.TurnPoints)
+	case squelch.EventSquelch:
+		return fmt.Sprintf("\t\t\trolled %v, SQUELCH, loses %v", e.Dice, e.TurnPoints)
+	case squelch.EventRollover:
+		return "\t\t\tscored every die, rolls all 6 again"
+	case squelch.EventReroll:
+		return fmt.Sprintf("\t\t\trolled %v, squelch saved by a reroll", e.Dice)
+	case squelch.EventOvertime:
+		return fmt.Sprintf("\t\t%v reached the target, final round", name(e.BotIndex))
+	case squelch.EventGameEnd:
+		if e.WinnerIndex < 0 {
+			return fmt.Sprintf("\t\tGame ended with an error: %v", e.Err)
+		}
+		return fmt.Sprintf("\t\t%v wins, scores %v", name(e.WinnerIndex), scoreList(e))
+	case squelch.EventMatchEnd:
+		wins := make([]string, len(e.Wins))
+		for i, w := range e.Wins {
+			wins[i] = fmt.Sprintf("%v %v", name(i), w)
+		}
+		return fmt.Sprintf("\tMatch over, wins: %v", strings.Join(wins, ", "))
+	}
+	return ""
+}
+
+// scoreList is every player's banked score, e.g. "alice 300, bob 0"
+func scoreList(e squelch.GameEvent) string {
+	s := make([]string, len(e.Scores))
+	for i, sc := range e.Scores {
+		p := fmt.Sprintf("bot %v", i)
+		if i < len(e.Players) && e.Players[i] != "" {
+			p = e.Players[i]
+		}
+		s[i] = fmt.Sprintf("%v %v", p, sc)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/config"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"github.com/dlclark/squelchbot-arena-go/store"
+)
+
+// defaultDB is where tournaments are saved unless -db says otherwise
+const defaultDB = "squelch-results.jsonl"
+
+// saveResults adds a finished tournament to the results store
+func saveResults(cfg *config.Config, c *store.Collector, p []squelch.Player, r *squelch.Results) (string, error) {
+	t := c.Tournament(cfg.GamesPerMatch, cfg.PlayersPerMatch, cfg.TargetScore, r)
+	t.Name = cfg.Name
+	for i, pl := range p {
+		// the names are already known, this is just for the version
+		if info, err := pl.Info(); err == nil {
+			t.Entrants[i].Version = info.Version
+		}
+	}
+	return t.ID, store.Open(cfg.Outputs.DB).Add(t)
+}
+
+// readResults reads the tournaments in the results file, warning about the
+// lines it had to skip
+func readResults(db string) ([]store.Tournament, error) {
+	ts, skipped, err := store.Open(db).Tournaments()
+	for _, s := range skipped {
+		log.Printf("Skipping %v", s)
+	}
+	return ts, err
+}
+
+// runHistory lists the tournaments played, or how one bot did in each
+func runHistory(args []string) {
+	fs := newFlagSet("history", "[flags]", "List the tournaments in the results file, newest first.")
+	db := fs.String("db", defaultDB, "the results file")
+	bot := fs.String("bot", "", "only show how this bot did")
+	limit := fs.Int("n", 20, "show the latest n tournaments, 0 for all")
+	fs.Parse(args)
+
+	ts, err := readResults(*db)
+	if err != nil {
+		log.Fatalf("Error reading results: %v", err)
+	}
+	if *limit > 0 && len(ts) > *limit {
+		ts = ts[len(ts)-*limit:]
+	}
+	if len(ts) == 0 {
+		fmt.Printf("No tournaments in %v.\n", *db)
+		return
+	}
+
+	// the store is oldest first
+	for i := len(ts) - 1; i >= 0; i-- {
+		t := ts[i]
+		line := fmt.Sprintf("%v  %v  %v entrants, %v matches", t.ID, t.Started.Format(time.RFC3339), len(t.Entrants), matchesPlayed(t))
+		if t.Incomplete {
+			line += " (incomplete)"
+		}
+
+		if *bot == "" {
+			if len(t.Stats) > 0 {
+				line += fmt.Sprintf(", won by %v", entrantLabel(t.Entrants[t.Stats[0].EntrantIndex]))
+			}
+			fmt.Println(line)
+			continue
+		}
+
+		for _, s := range t.Stats {
+			e := t.Entrants[s.EntrantIndex]
+			if e.Name != *bot {
+				continue
+			}
+			fmt.Printf("%v\n\t%v: rank %v of %v, won %4.1f%% match, %4.1f%% game\n", line, entrantLabel(e),
+				s.Rank, len(t.Stats), s.MatchRate()*100, s.GameRate()*100)
+		}
+	}
+}
+
+// runLeaderboard ranks every bot version over every tournament
+func runLeaderboard(args []string) {
+	fs := newFlagSet("leaderboard", "[flags]", "Rank every bot version over the tournaments in the results file.")
+	db := fs.String("db", defaultDB, "the results file")
+	since := fs.Duration("since", 0, "only count tournaments started within this long, e.g. 336h")
+	fs.Parse(args)
+
+	ts, err := readResults(*db)
+	if err != nil {
+		log.Fatalf("Error reading results: %v", err)
+	}
+	if *since > 0 {
+		cutoff := time.Now().Add(-*since)
+		recent := ts[:0]
+		for _, t := range ts {
+			if t.Started.After(cutoff) {
+				recent = append(recent, t)
+			}
+		}
+		ts = recent
+	}
+
+	board := store.Leaderboard(ts)
+	if len(board) == 0 {
+		fmt.Printf("No tournaments in %v.\n", *db)
+		return
+	}
+
+	fmt.Printf("Leaderboard over %v tournaments:\n", len(ts))
+	for i, s := range board {
+		fmt.Printf("\t%v: %-30v won %4.1f%% match, %4.1f%% game in %v tournaments (%v firsts), last played %v\n", i+1,
+			entrantLabel(store.Entrant{Name: s.Name, Version: s.Version}), s.MatchRate()*100, s.GameRate()*100,
+			s.Tournaments, s.Firsts, s.LastPlayed.Format("2006-01-02"))
+	}
+}
+
+// runShow prints a single tournament
+func runShow(args []string) {
+	fs := newFlagSet("show", "[flags] <tournament id>", "Show a tournament from the results file, a prefix of its id will do.")
+	db := fs.String("db", defaultDB, "the results file")
+	matches := fs.Bool("matches", false, "list every match too")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatalf("Invalid input: show needs a tournament id")
+	}
+
+	t, err := store.Open(*db).Get(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error reading results: %v", err)
+	}
+
+	fmt.Printf("Tournament %v\n", t.ID)
+	fmt.Printf("Played %v, took %v\n", t.Started.Format(time.RFC3339), t.Finished.Sub(t.Started).Round(time.Second))
+	fmt.Printf("%v entrants, %v players per match, %v games per match to %v, %v matches\n",
+		len(t.Entrants), t.PlayersPerMatch, t.GamesPerMatch, t.TargetScore, matchesPlayed(*t))
+	if t.Incomplete {
+		fmt.Println("Stopped early, not every match was played.")
+	}
+
+	fmt.Println("Ranks:")
+	for _, s := range t.Stats {
+		e := t.Entrants[s.EntrantIndex]
+		if s.Matches == 0 {
+			fmt.Printf("\t%v: %v (no matches finished)\n", s.Rank, entrantLabel(e))
+			continue
+		}
+		fmt.Printf("\t%v: %v (won %4.1f%% match, %4.1f%% game, %v squelches in %v turns)\n", s.Rank, entrantLabel(e),
+			s.MatchRate()*100, s.GameRate()*100, s.Squelches, s.Turns)
+	}
+
+	if !*matches {
+		return
+	}
+	fmt.Println("Matches:")
+	for _, m := range t.Matches {
+		wins := make([]string, len(m.Players))
+		for i, p := range m.Players {
+			wins[i] = fmt.Sprintf("%v %v", p, m.Wins[i])
+		}
+		fmt.Printf("\t%v: %v\n", m.ID, strings.Join(wins, ", "))
+	}
+}
+
+// matchesPlayed counts the finished matches from the stats, which include
+// matches that weren't recorded game by game
+func matchesPlayed(t store.Tournament) int {
+	n := 0
+	for _, s := range t.Stats {
+		n += s.Matches
+	}
+	if t.PlayersPerMatch > 0 {
+		n /= t.PlayersPerMatch
+	}
+	return n
+}
+
+func entrantLabel(e store.Entrant) string {
+	if e.Version == "" {
+		return e.Name
+	}
+	return e.Name + "@" + e.Version
+}
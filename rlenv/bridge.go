@@ -0,0 +1,68 @@
+package rlenv
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Request is a single command to the stdio bridge, one JSON object per line:
+//
+//	{"cmd": "reset"}
+//	{"cmd": "step", "option": 0, "stay": false}
+//	{"cmd": "close"}
+type Request struct {
+	Cmd string `json:"cmd"`
+	Action
+}
+
+// Response is the bridge's reply to a Request, one JSON object per line
+type Response struct {
+	StepResult
+	Error string `json:"error,omitempty"`
+}
+
+// Serve runs the environment over line-delimited JSON so trainers in other
+// languages can drive it. It returns when r is exhausted or a close command
+// is received.
+func Serve(env *Env, r io.Reader, w io.Writer) error {
+	defer env.Close()
+
+	in := bufio.NewScanner(r)
+	out := json.NewEncoder(w)
+
+	for in.Scan() {
+		req := Request{}
+		resp := Response{}
+
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			switch req.Cmd {
+			case "reset":
+				obs, err := env.Reset()
+				resp.Observation = obs
+				if err != nil {
+					resp.Error = err.Error()
+				}
+			case "step":
+				res, err := env.Step(req.Action)
+				resp.StepResult = res
+				if err != nil {
+					resp.Error = err.Error()
+				}
+			case "close":
+				return out.Encode(resp)
+			default:
+				resp.Error = fmt.Sprintf("unknown cmd %q", req.Cmd)
+			}
+		}
+
+		if err := out.Encode(resp); err != nil {
+			return err
+		}
+	}
+
+	return in.Err()
+}
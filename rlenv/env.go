@@ -0,0 +1,226 @@
+// Package rlenv wraps a squelch game in a gym-style reset/step environment
+// so learning agents can play against any squelch.Player.
+package rlenv
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// Observation is what the agent sees when it has to make a choice
+type Observation struct {
+	MyScore int `json:"myScore"`
+	// OpponentScores are the opponents' scores in game order, as of their
+	// last turn
+	OpponentScores []int                   `json:"opponentScores"`
+	TurnPoints     int                     `json:"turnPoints"`
+	DiceCount      int                     `json:"diceCount"`
+	Dice           string                  `json:"dice"`
+	Options        []squelch.ScoringOption `json:"options"`
+	IsFinalRound   bool                    `json:"isFinalRound"`
+	TargetScore    int                     `json:"targetScore"`
+}
+
+// Action is the agent's choice: the index into Observation.Options to take
+// and if it stays afterwards
+type Action struct {
+	Option int  `json:"option"`
+	Stay   bool `json:"stay"`
+}
+
+// StepResult is the outcome of an action. Reward is 1 for a win and -1 for a
+// loss when the game is done, and 0 otherwise.
+type StepResult struct {
+	Observation *Observation `json:"observation"`
+	Reward      float64      `json:"reward"`
+	Done        bool         `json:"done"`
+	// Winner is the index of the winning player in game order, the agent is 0
+	Winner int `json:"winner"`
+}
+
+// errAbandoned ends the running game when the environment resets or closes
+var errAbandoned = errors.New("episode abandoned")
+
+// Env is a squelch game where the agent is player 0 and the opponents follow.
+// Each episode is one game; the starting player alternates between episodes.
+type Env struct {
+	opponents   []squelch.Player
+	targetScore int
+	rng         *rand.Rand
+	episode     int
+
+	agent   *agentPlayer
+	results chan gameOutcome
+	current *Observation
+}
+
+type gameOutcome struct {
+	res squelch.GameResult
+	err error
+}
+
+// New makes an environment against the opponents. The seed drives every roll
+// so episodes are reproducible.
+func New(targetScore int, seed int64, opponents ...squelch.Player) (*Env, error) {
+	if len(opponents) == 0 {
+		return nil, errors.New("at least 1 opponent is required")
+	}
+
+	return &Env{
+		opponents:   opponents,
+		targetScore: targetScore,
+		rng:         rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Reset abandons any game in progress, starts a new one and returns the
+// agent's first observation.
+func (e *Env) Reset() (*Observation, error) {
+	e.Close()
+
+	for {
+		e.episode++
+		e.agent = &agentPlayer{
+			targetScore:   e.targetScore,
+			opponentCount: len(e.opponents),
+			obs:           make(chan *Observation),
+			actions:       make(chan Action),
+			quit:          make(chan struct{}),
+		}
+		e.results = make(chan gameOutcome, 1)
+
+		players := append([]squelch.Player{e.agent}, e.opponents...)
+		g := squelch.NewGame(players, e.targetScore, "rlenv", strconv.Itoa(e.episode), (e.episode-1)%len(players))
+		g.SetRollFunc(squelch.RandRoller(e.rng))
+
+		go func(results chan gameOutcome) {
+			res, err := g.Run()
+			results <- gameOutcome{res, err}
+		}(e.results)
+
+		res, err := e.wait()
+		if err != nil {
+			return nil, err
+		}
+		if !res.Done {
+			return res.Observation, nil
+		}
+		// the game ended without the agent making a single choice, go again
+	}
+}
+
+// Step takes the agent's action and plays until it has to choose again or
+// the game ends.
+func (e *Env) Step(a Action) (StepResult, error) {
+	if e.current == nil {
+		return StepResult{}, errors.New("no game in progress, call Reset")
+	}
+	if a.Option < 0 || a.Option >= len(e.current.Options) {
+		return StepResult{}, fmt.Errorf("option %v out of range, %v options", a.Option, len(e.current.Options))
+	}
+
+	e.agent.actions <- a
+	return e.wait()
+}
+
+// Close abandons any game in progress
+func (e *Env) Close() {
+	if e.agent == nil {
+		return
+	}
+
+	close(e.agent.quit)
+	<-e.results
+	e.agent = nil
+	e.current = nil
+}
+
+// wait blocks until the agent has to choose or the game is over
+func (e *Env) wait() (StepResult, error) {
+	select {
+	case obs := <-e.agent.obs:
+		e.current = obs
+		return StepResult{Observation: obs}, nil
+	case out := <-e.results:
+		e.agent = nil
+		e.current = nil
+		if out.err != nil {
+			return StepResult{}, fmt.Errorf("player %v: %v", out.res.ErrIndex, out.err)
+		}
+
+		reward := -1.0
+		if out.res.WinnerIndex == 0 {
+			reward = 1
+		}
+		return StepResult{Reward: reward, Done: true, Winner: out.res.WinnerIndex}, nil
+	}
+}
+
+// agentPlayer hands every choice to the environment and waits for the action
+type agentPlayer struct {
+	targetScore   int
+	opponentCount int
+	obs           chan *Observation
+	actions       chan Action
+	quit          chan struct{}
+}
+
+func (p *agentPlayer) Info() (*squelch.PlayerInfo, error) {
+	return &squelch.PlayerInfo{Name: "Agent"}, nil
+}
+
+func (p *agentPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
+	return nil
+}
+
+func (p *agentPlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	return nil
+}
+
+func (p *agentPlayer) GameStart(matchID, gameID string) error {
+	return nil
+}
+
+func (p *agentPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	return nil
+}
+
+func (p *agentPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, state squelch.GameState) error {
+	return nil
+}
+
+func (p *agentPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, state squelch.GameState) (*squelch.PlayerChoice, error) {
+	obs := &Observation{
+		MyScore:        state.Score(),
+		OpponentScores: make([]int, p.opponentCount),
+		TurnPoints:     state.TurnPoints,
+		DiceCount:      len(dieValues),
+		Dice:           dieValues,
+		Options:        options,
+		IsFinalRound:   state.IsFinalRound,
+		TargetScore:    p.targetScore,
+	}
+	// the agent is bot 0 and the opponents follow
+	copy(obs.OpponentScores, state.Scores[1:])
+
+	select {
+	case p.obs <- obs:
+	case <-p.quit:
+		return nil, errAbandoned
+	}
+
+	select {
+	case a := <-p.actions:
+		return &squelch.PlayerChoice{TakeOptionID: options[a.Option].ID, Stay: a.Stay}, nil
+	case <-p.quit:
+		return nil, errAbandoned
+	}
+}
+
+func (p *agentPlayer) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	return nil
+}
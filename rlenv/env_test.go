@@ -0,0 +1,116 @@
+package rlenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+)
+
+// playEpisode takes the best option each roll and stays at 300 points
+func playEpisode(t *testing.T, e *Env) ([]string, StepResult) {
+	obs, err := e.Reset()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	rolls := []string{}
+	for {
+		rolls = append(rolls, obs.Dice)
+		res, err := e.Step(Action{Option: 0, Stay: obs.TurnPoints+obs.Options[0].Points >= 300})
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if res.Done {
+			return rolls, res
+		}
+		obs = res.Observation
+	}
+}
+
+func TestEnv_Episodes(t *testing.T) {
+	opp, _ := localbot.NewBuiltinPlayer("threshold")
+	e, err := New(1000, 42, opp)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		_, res := playEpisode(t, e)
+		if res.Reward != 1 && res.Reward != -1 {
+			t.Errorf("episode %v: reward incorrect, got %v", i, res.Reward)
+		}
+		if (res.Reward == 1) != (res.Winner == 0) {
+			t.Errorf("episode %v: reward %v doesn't match winner %v", i, res.Reward, res.Winner)
+		}
+	}
+}
+
+func TestEnv_Seeded(t *testing.T) {
+	opp, _ := localbot.NewBuiltinPlayer("threshold")
+	e1, _ := New(1000, 7, opp)
+	e2, _ := New(1000, 7, opp)
+
+	r1, _ := playEpisode(t, e1)
+	r2, _ := playEpisode(t, e2)
+	if strings.Join(r1, ",") != strings.Join(r2, ",") {
+		t.Errorf("same seed should give the same rolls:\n%v\n%v", r1, r2)
+	}
+}
+
+func TestEnv_ResetMidGame(t *testing.T) {
+	opp, _ := localbot.NewBuiltinPlayer("threshold")
+	e, _ := New(1000, 1, opp)
+
+	if _, err := e.Reset(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// abandon the first game without finishing it
+	if _, err := e.Reset(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := e.Step(Action{Option: 99}); err == nil {
+		t.Errorf("expected an out of range option to fail")
+	}
+	e.Close()
+}
+
+func TestServe(t *testing.T) {
+	opp, _ := localbot.NewBuiltinPlayer("threshold")
+	e, _ := New(1000, 1, opp)
+
+	in := strings.NewReader(`{"cmd":"reset"}
+{"cmd":"step","option":0,"stay":true}
+{"cmd":"bogus"}
+{"cmd":"close"}
+`)
+	out := &bytes.Buffer{}
+	if err := Serve(e, in, out); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	dec := json.NewDecoder(out)
+	resp := []Response{}
+	for dec.More() {
+		r := Response{}
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		resp = append(resp, r)
+	}
+
+	if want, got := 4, len(resp); want != got {
+		t.Fatalf("Response count incorrect, want %v got %v", want, got)
+	}
+	if resp[0].Observation == nil || len(resp[0].Observation.Options) == 0 {
+		t.Errorf("reset should return an observation, got %+v", resp[0])
+	}
+	if resp[1].Error != "" || (resp[1].Observation == nil && !resp[1].Done) {
+		t.Errorf("step incorrect, got %+v", resp[1])
+	}
+	if resp[2].Error == "" {
+		t.Errorf("expected an error for an unknown cmd")
+	}
+}
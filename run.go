@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/config"
+	"github.com/dlclark/squelchbot-arena-go/distrib"
+	"github.com/dlclark/squelchbot-arena-go/metrics"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"github.com/dlclark/squelchbot-arena-go/store"
+	"github.com/dlclark/squelchbot-arena-go/web"
+)
+
+// the run command's flags
+var (
+	runFS = newFlagSet("run", "[flags]", "Play a round-robin tournament between the bots and rank them.")
+
+	configPath = runFS.String("config", "", "a YAML or JSON tournament config file, the flags given with it override it")
+	gpm        = runFS.Int("gpm", config.DefaultGamesPerMatch, "games per match")
+	ppm        = runFS.Int("ppm", 0, "players per match, 0 for every entrant")
+	auth       = runFS.String("auth", "", "JSON file of per-URL bot auth settings (secret, bearerToken, certFile, keyFile, caFile)")
+
+	preflight     = runFS.Bool("preflight", true, "health check every bot before the tournament starts")
+	dropUnhealthy = runFS.Bool("drop-unhealthy", false, "drop bots that fail the preflight check instead of aborting")
+
+	autoHandicap = runFS.Bool("auto-handicap", false, "give weaker bots a head start from how they've done in the -db results file")
+
+	jsonOut = runFS.String("json", "", "write the tournament results as JSON to this file")
+	db      = runFS.String("db", defaultDB, "add the tournament to this results file for history and leaderboard, empty to not")
+
+	checkpoint = runFS.String("checkpoint", "", "write every finished match to this file so the tournament can be resumed")
+	grace      = runFS.Duration("grace", 30*time.Second, "on Ctrl-C, how long to let games in progress finish")
+	resume     = runFS.Bool("resume", false, "skip the matches already in the -checkpoint file and count their results")
+
+	record = runFS.String("record", "", "record every match event as JSON lines to this file")
+
+	metricsAddr = runFS.String("metrics", "", "serve Prometheus metrics on this address at /metrics, e.g. localhost:9090")
+
+	webAddr  = runFS.String("web", "", "serve a page to watch the tournament live on this address, e.g. localhost:8080")
+	webDelay = runFS.Duration("web-delay", 500*time.Millisecond, "pause after each roll and choice while spectators are watching")
+
+	coordAddr = runFS.String("coordinator", "", "hand the matches out to workers connecting on this address, e.g. :7070")
+	lease     = runFS.Duration("lease", 5*time.Minute, "how long a worker has to finish a match before it's handed to another")
+	workerTok = runFS.String("worker-token", "", "the shared token workers must send to the -coordinator")
+)
+
+// runTournament plays a round-robin tournament between the bots and ranks them
+func runTournament(args []string) {
+	bots := addBotFlags(runFS)
+	lf := newLogFlags(runFS)
+	runFS.Parse(args)
+
+	if err := lf.setup(); err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	cfg, err := tournamentConfig(*bots)
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	// setup our match
+	p, err := cfg.Players()
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	if cfg.Seed != nil {
+		rand.Seed(*cfg.Seed)
+	} else {
+		rand.Seed(time.Now().UTC().UnixNano())
+	}
+
+	if *coordAddr != "" && *workerTok == "" {
+		log.Fatalf("Invalid input: -worker-token is required with -coordinator")
+	}
+
+	t := squelch.NewTournament(cfg.GamesPerMatch, cfg.PlayersPerMatch, cfg.TargetScore, p)
+	t.SetConcurrency(cfg.Concurrency)
+	teams := cfg.Teams()
+	if teams != nil {
+		if err := t.SetTeams(teams); err != nil {
+			log.Fatalf("Invalid input: %v", err)
+		}
+		if *coordAddr != "" {
+			log.Fatalf("Invalid input: team tournaments can't be played by workers yet")
+		}
+	}
+	handicaps := cfg.Handicaps()
+
+	if *preflight {
+		reports := t.Preflight()
+		printHealth(reports)
+
+		unhealthy := 0
+		for _, r := range reports {
+			if !r.Healthy() {
+				unhealthy++
+			}
+		}
+
+		if unhealthy > 0 {
+			if !*dropUnhealthy {
+				log.Fatalf("%v bot(s) failed the preflight check", unhealthy)
+			}
+			if teams != nil {
+				log.Fatalf("%v bot(s) failed the preflight check, bots can't be dropped from teams", unhealthy)
+			}
+			p, handicaps, err = dropUnhealthyBots(cfg, t, p, handicaps, reports)
+			if err != nil {
+				log.Fatalf("Not enough healthy bots: %v", err)
+			}
+		}
+	}
+
+	if cfg.AutoHandicap {
+		handicaps, err = ratedHandicaps(cfg, p)
+		if err != nil {
+			log.Fatalf("Error with handicaps: %v", err)
+		}
+	}
+	if handicaps != nil {
+		if err := t.SetHandicaps(handicaps); err != nil {
+			log.Fatalf("Invalid input: %v", err)
+		}
+		if *coordAddr != "" {
+			log.Fatalf("Invalid input: handicaps can't be played by workers yet")
+		}
+	}
+
+	if cfg.Outputs.Checkpoint != "" {
+		if err := setupCheckpoint(t, cfg.Outputs.Checkpoint, *resume); err != nil {
+			log.Fatalf("Error with checkpoint: %v", err)
+		}
+	} else if *resume {
+		log.Fatalf("Invalid input: -resume needs a -checkpoint file")
+	}
+
+	var rec *squelch.Recorder
+	if cfg.Outputs.Record != "" {
+		f, err := os.Create(cfg.Outputs.Record)
+		if err != nil {
+			log.Fatalf("Error recording: %v", err)
+		}
+		defer f.Close()
+		rec = squelch.NewRecorder(f)
+		t.AddObserver(rec)
+	}
+
+	if *metricsAddr != "" {
+		c := metrics.NewCollector()
+		t.AddObserver(c)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", c)
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				log.Printf("Error serving metrics: %v", err)
+			}
+		}()
+		fmt.Printf("Metrics on http://%v/metrics\n", *metricsAddr)
+	}
+
+	if *webAddr != "" {
+		s := web.NewServer(cfg.TargetScore, nil, newBot)
+		t.AddObserver(s.Spectate(*webDelay))
+		go func() {
+			if err := http.ListenAndServe(*webAddr, s.Handler()); err != nil {
+				log.Printf("Error serving spectators: %v", err)
+			}
+		}()
+		fmt.Printf("Watch live on http://%v\n", *webAddr)
+	}
+
+	var collector *store.Collector
+	if cfg.Outputs.DB != "" {
+		collector = store.NewCollector()
+		t.AddObserver(collector)
+	}
+
+	var coord *distrib.Coordinator
+	if *coordAddr != "" {
+		names, err := entrantNames(p)
+		if err != nil {
+			log.Fatalf("Error with coordinator: %v", err)
+		}
+		coord = distrib.NewCoordinator(names, cfg.GamesPerMatch, cfg.TargetScore, *lease, *workerTok)
+		t.SetMatchRunner(coord.RunMatch)
+		srv := &http.Server{Addr: *coordAddr, Handler: coord.Handler()}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Error serving workers: %v", err)
+			}
+		}()
+		defer srv.Close()
+		fmt.Printf("Waiting for workers on %v\n", *coordAddr)
+	}
+
+	// number of matches to have an even tournament
+	mc := t.GetMatchCount()
+
+	// total game count
+	totalGc := mc * cfg.GamesPerMatch
+
+	// print our summary line
+	if cfg.Name != "" {
+		fmt.Printf("%v\n", cfg.Name)
+	}
+	fmt.Printf("Starting tournament with %v entrants, %v players per match, %v matches totaling %v games.\n", t.GetEntrantCount(), cfg.PlayersPerMatch, mc, totalGc)
+	if teams != nil {
+		names, err := entrantNames(p)
+		if err != nil {
+			log.Fatalf("Error getting bot names: %v", err)
+		}
+		fmt.Println("Teams:")
+		for _, team := range teams {
+			members := make([]string, len(team))
+			for i, e := range team {
+				members[i] = names[e]
+			}
+			fmt.Printf("\t%v: %v\n", cfg.Entrants[team[0]].Team, strings.Join(members, ", "))
+		}
+	}
+	if handicaps != nil {
+		printHandicaps(p, handicaps)
+	}
+
+	// Ctrl-C stops the tournament but keeps what's finished, a second one
+	// quits right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+		fmt.Printf("Stopping, finishing the games in progress for up to %v. Press Ctrl-C again to quit now.\n", *grace)
+	}()
+	t.SetGracePeriod(*grace)
+
+	// start the tournament!
+	r, err := t.RunContext(ctx)
+	if coord != nil {
+		coord.Close()
+		// keep answering a little longer so workers between matches hear
+		// that the tournament is over
+		time.Sleep(2 * time.Second)
+	}
+	if err != nil {
+		fmt.Printf("An error running the tournament: %v\n", err)
+	}
+
+	if r == nil {
+		return
+	}
+
+	if err := t.CheckpointErr(); err != nil {
+		fmt.Printf("An error writing the checkpoint: %v\n", err)
+	}
+	if rec != nil && rec.Err() != nil {
+		fmt.Printf("An error writing the recording, it's incomplete: %v\n", rec.Err())
+	}
+
+	if r.Incomplete {
+		fmt.Printf("Tournament stopped early, %v of %v matches finished.\n", r.MatchesFinished, mc)
+		if cfg.Outputs.Checkpoint != "" {
+			fmt.Println("Run again with -resume to play the rest.")
+		}
+	}
+
+	// output final points
+	fmt.Println("Ranks:")
+	for i, s := range r.Points {
+		if s.Matches == 0 {
+			fmt.Printf("\t%v: %v (no matches finished)\n", i+1, r.EntrantNames[s.EntrantIndex])
+			continue
+		}
+		fmt.Printf("\t%v: %v (won %4.1f%% match, %4.1f%% game)\n", i+1, r.EntrantNames[s.EntrantIndex],
+			float64(s.Points*100.0)/float64(s.Matches), float64(s.TotalWins*100.0)/float64(s.Matches*cfg.GamesPerMatch))
+	}
+
+	printLatency(r)
+
+	if cfg.Outputs.JSON != "" {
+		if err := writeJSON(cfg.Outputs.JSON, r); err != nil {
+			log.Fatalf("Error writing results: %v", err)
+		}
+	}
+
+	if collector != nil && r.MatchesFinished > 0 {
+		id, err := saveResults(cfg, collector, p, r)
+		if err != nil {
+			log.Fatalf("Error saving results: %v", err)
+		}
+		fmt.Printf("Saved as tournament %v in %v.\n", id, cfg.Outputs.DB)
+	}
+}
+
+// setupCheckpoint starts the checkpoint file, carrying over the matches
+// already played when resuming
+func setupCheckpoint(t *squelch.Tournament, path string, resume bool) error {
+	if resume {
+		f, err := os.Open(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		var header squelch.CheckpointHeader
+		var done []squelch.MatchResult
+		if err == nil {
+			header, done, err = squelch.ReadCheckpoint(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+		if err := t.Resume(header, done); err != nil {
+			return err
+		}
+		fmt.Printf("Resuming with %v of %v matches already played.\n", len(done), t.GetMatchCount())
+	}
+
+	// rewrite what we resumed so a partly written last line doesn't get
+	// stuck in the middle of the file
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	return t.SetCheckpoint(f)
+}
+
+// printLatency outputs each bot's response times by callback
+func printLatency(r *squelch.Results) {
+	fmt.Println("Latency (p50/p95/p99/max):")
+	for _, l := range r.Latency {
+		fmt.Printf("\t%v (%v timeouts)\n", r.EntrantNames[l.EntrantIndex], l.Timeouts)
+
+		cbs := make([]string, 0, len(l.Callbacks))
+		for cb := range l.Callbacks {
+			cbs = append(cbs, cb)
+		}
+		sort.Strings(cbs)
+
+		for _, cb := range cbs {
+			s := l.Callbacks[cb]
+			fmt.Printf("\t\t%-10v %8v %8v %8v %8v (%v calls)\n", cb,
+				s.P50.Round(time.Microsecond), s.P95.Round(time.Microsecond),
+				s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond), s.Calls)
+		}
+	}
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// tournamentConfig makes the tournament config from the -config file and
+// the flags. Flags given on the command line override the file.
+func tournamentConfig(bots specs) (*config.Config, error) {
+	entrants, err := bots.entrants()
+	if err != nil {
+		return nil, err
+	}
+
+	if *configPath == "" {
+		cfg := &config.Config{
+			GamesPerMatch:   *gpm,
+			PlayersPerMatch: *ppm,
+			Auth:            *auth,
+			AutoHandicap:    *autoHandicap,
+			Entrants:        entrants,
+			Outputs: config.Outputs{
+				JSON:       *jsonOut,
+				Record:     *record,
+				Checkpoint: *checkpoint,
+				DB:         *db,
+			},
+		}
+		cfg.SetDefaults()
+		return cfg, cfg.Validate()
+	}
+
+	if len(entrants) > 0 {
+		return nil, errors.New("the entrants come from the -config file, -bot, -url and -script can't be added")
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	runFS.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "gpm":
+			cfg.GamesPerMatch = *gpm
+		case "ppm":
+			cfg.PlayersPerMatch = *ppm
+			cfg.EveryEntrant = false
+			if *ppm == 0 {
+				cfg.SetDefaults()
+			}
+		case "auth":
+			cfg.Auth = *auth
+		case "auto-handicap":
+			cfg.AutoHandicap = *autoHandicap
+		case "json":
+			cfg.Outputs.JSON = *jsonOut
+		case "record":
+			cfg.Outputs.Record = *record
+		case "checkpoint":
+			cfg.Outputs.Checkpoint = *checkpoint
+		case "db":
+			cfg.Outputs.DB = *db
+		}
+	})
+	return cfg, cfg.Validate()
+}
+
+// dropUnhealthyBots takes the bots that failed the preflight check out of
+// the tournament, along with their handicaps. If every entrant was playing
+// in every match, the ones left still do.
+func dropUnhealthyBots(cfg *config.Config, t *squelch.Tournament, p []squelch.Player, handicaps []squelch.Handicap, reports []squelch.HealthReport) ([]squelch.Player, []squelch.Handicap, error) {
+	t.DropUnhealthy(reports)
+	healthy := p[:0:0]
+	var kept []squelch.Handicap
+	for _, r := range reports {
+		if r.Healthy() {
+			healthy = append(healthy, p[r.EntrantIndex])
+			if handicaps != nil {
+				kept = append(kept, handicaps[r.EntrantIndex])
+			}
+		}
+	}
+
+	if cfg.EveryEntrant {
+		cfg.PlayersPerMatch = len(healthy)
+		t.SetPlayersPerMatch(len(healthy))
+	}
+	return healthy, kept, validateEntrants(t.GetEntrantCount(), cfg.PlayersPerMatch)
+}
+
+// ratedHandicaps gives the bots handicaps from how they've done in the
+// tournaments in the results file
+func ratedHandicaps(cfg *config.Config, p []squelch.Player) ([]squelch.Handicap, error) {
+	ts, err := readResults(cfg.Outputs.DB)
+	if err != nil {
+		return nil, err
+	}
+	bots := make([]store.Entrant, len(p))
+	for i, pl := range p {
+		info, err := pl.Info()
+		if err != nil {
+			return nil, err
+		}
+		bots[i] = store.Entrant{Name: info.Name, Version: info.Version}
+	}
+	return store.AutoHandicaps(ts, bots, cfg.TargetScore), nil
+}
+
+// printHandicaps lists the bots that have a handicap
+func printHandicaps(p []squelch.Player, handicaps []squelch.Handicap) {
+	names, err := entrantNames(p)
+	if err != nil {
+		log.Fatalf("Error getting bot names: %v", err)
+	}
+	fmt.Println("Handicaps:")
+	for i, h := range handicaps {
+		if h == (squelch.Handicap{}) {
+			continue
+		}
+		fmt.Printf("\t%v: start with %v, target lowered %v, %v reroll(s)\n", names[i], h.StartScore, h.TargetReduction, h.Rerolls)
+	}
+}
+
+// entrantNames asks every bot its name
+func entrantNames(p []squelch.Player) ([]string, error) {
+	names := make([]string, len(p))
+	for i, pl := range p {
+		info, err := pl.Info()
+		if err != nil {
+			return nil, err
+		}
+		names[i] = info.Name
+	}
+	return names, nil
+}
+
+func validateEntrants(entrants, ppm int) error {
+	if entrants < 2 {
+		return errors.New("at least 2 entrants are required")
+	}
+
+	if ppm > entrants {
+		return fmt.Errorf("players per match cannot exceed entrant count (%v)", entrants)
+	}
+
+	return nil
+}
+
+func printHealth(reports []squelch.HealthReport) {
+	fmt.Println("Preflight:")
+	for _, r := range reports {
+		status := "healthy"
+		if !r.Healthy() {
+			status = fmt.Sprintf("UNHEALTHY: %v", r.Err)
+		}
+		fmt.Printf("\t%v: %-20v ping %8v, game %8v, %v\n", r.EntrantIndex, r.Name,
+			r.Latency.Round(time.Microsecond), r.GameDuration.Round(time.Microsecond), status)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/config"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestDropUnhealthyBots_DefaultPlayersPerMatch(t *testing.T) {
+	// nothing listens on port 1 so the URL bot fails the preflight check
+	cfg, err := config.Parse([]byte(`
+gamesPerMatch: 2
+entrants:
+  - builtin: threshold
+  - url: http://127.0.0.1:1/
+  - builtin: maxev
+`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	p, err := cfg.Players()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	tour := squelch.NewTournament(cfg.GamesPerMatch, cfg.PlayersPerMatch, cfg.TargetScore, p)
+	reports := tour.Preflight()
+	if reports[1].Healthy() {
+		t.Fatalf("The URL bot should fail the preflight check")
+	}
+
+	p, _, err = dropUnhealthyBots(cfg, tour, p, nil, reports)
+	if err != nil {
+		t.Fatalf("Dropping a bot should leave a playable tournament, got %v", err)
+	}
+	if len(p) != 2 || cfg.PlayersPerMatch != 2 || tour.GetEntrantCount() != 2 || tour.GetMatchCount() != 1 {
+		t.Fatalf("Every healthy bot should play the one match, got %v bots, %v per match, %v matches",
+			len(p), cfg.PlayersPerMatch, tour.GetMatchCount())
+	}
+
+	r, err := tour.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if r.MatchesFinished != 1 {
+		t.Errorf("The match should be played, got %v", r.MatchesFinished)
+	}
+}
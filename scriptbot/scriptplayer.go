@@ -0,0 +1,341 @@
+package scriptbot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"go.starlark.net/starlark"
+)
+
+var _ squelch.Player = &ScriptPlayer{}
+
+// maxSteps is how many Starlark steps a script gets for each call, far more
+// than any sensible strategy needs
+const maxSteps = 100000000
+
+// ScriptPlayer is a squelch player whose strategy lives in a Starlark script.
+// The script must define a function:
+//
+//	def choose(dice, options, state):
+//	    return (option_id, stay)
+//
+// dice is the sorted roll (e.g. "11256"), options is a list of dicts with
+// "id", "dice" and "points" keys, and state is a dict with "turn_points",
+// "score", "scores", "high_score", "target_score" and "is_final_round".
+// The script may also set a global "name" to override the player name. The
+// options it was given, if any, are in the predeclared dict "options".
+//
+// The script is re-read at the start of every match if it changed on disk.
+// Each call into the script has a budget of maxSteps Starlark steps and
+// must finish within the timeout, so a script that loops forever loses the
+// game instead of hanging the tournament.
+type ScriptPlayer struct {
+	path    string
+	options starlark.StringDict
+	timeout time.Duration
+
+	sync    *sync.RWMutex
+	modTime time.Time
+	name    string
+	choose  starlark.Callable
+
+	data map[string]*matchState
+}
+
+// NewScriptPlayer loads the strategy script at path and returns a player for it.
+func NewScriptPlayer(path string) (*ScriptPlayer, error) {
+	return NewScriptPlayerWithOptions(path, nil)
+}
+
+// NewScriptPlayerWithOptions loads the strategy script at path with options
+// for the script to read, so one script can play several ways.
+func NewScriptPlayerWithOptions(path string, options map[string]string) (*ScriptPlayer, error) {
+	opts := starlark.NewDict(len(options))
+	for k, v := range options {
+		opts.SetKey(starlark.String(k), starlark.String(v))
+	}
+	opts.Freeze()
+
+	p := &ScriptPlayer{
+		path:    path,
+		options: starlark.StringDict{"options": opts},
+		timeout: 10 * time.Second,
+		sync:    &sync.RWMutex{},
+		data:    make(map[string]*matchState),
+	}
+
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SetTimeout sets how long the script has for each call, 10 seconds by
+// default
+func (p *ScriptPlayer) SetTimeout(d time.Duration) {
+	p.sync.Lock()
+	p.timeout = d
+	p.sync.Unlock()
+}
+
+func (p *ScriptPlayer) Info() (*squelch.PlayerInfo, error) {
+	p.sync.RLock()
+	defer p.sync.RUnlock()
+	return &squelch.PlayerInfo{Name: p.name}, nil
+}
+
+func (p *ScriptPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
+	// pick up any edits made since the last match
+	if err := p.reload(); err != nil {
+		return err
+	}
+
+	p.sync.Lock()
+	p.data[matchID] = &matchState{
+		targetScore: maxPoints,
+		games:       make(map[string]*gameState),
+	}
+	p.sync.Unlock()
+	return nil
+}
+
+func (p *ScriptPlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	p.sync.Lock()
+	delete(p.data, matchID)
+	p.sync.Unlock()
+	return nil
+}
+
+func (p *ScriptPlayer) GameStart(matchID, gameID string) error {
+	return nil
+}
+
+func (p *ScriptPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	p.sync.Lock()
+	if m, ok := p.data[matchID]; ok {
+		delete(m.games, gameID)
+	}
+	p.sync.Unlock()
+	return nil
+}
+
+func (p *ScriptPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, game squelch.GameState) error {
+	state := p.getState(matchID, gameID)
+
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	state.startPoints = startPoints
+	state.turnPoints = 0
+	state.isFinalRound = isFinalRound
+	for _, t := range otherPlayerTurns {
+		state.scores[t.BotIndex] = t.EndPoints
+	}
+
+	return nil
+}
+
+func (p *ScriptPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, game squelch.GameState) (*squelch.PlayerChoice, error) {
+	state := p.getState(matchID, gameID)
+
+	p.sync.RLock()
+	choose := p.choose
+	p.sync.RUnlock()
+
+	opts := make([]starlark.Value, len(options))
+	for i, o := range options {
+		d := starlark.NewDict(3)
+		d.SetKey(starlark.String("id"), starlark.String(o.ID))
+		d.SetKey(starlark.String("dice"), starlark.String(o.DieValues))
+		d.SetKey(starlark.String("points"), starlark.MakeInt(o.Points))
+		opts[i] = d
+	}
+
+	thread, done := p.thread()
+	ret, err := starlark.Call(thread, choose, starlark.Tuple{
+		starlark.String(dieValues),
+		starlark.NewList(opts),
+		p.stateDict(state),
+	}, nil)
+	if err = done(err); err != nil {
+		return nil, fmt.Errorf("script %v: %w", p.path, err)
+	}
+
+	choice, err := toChoice(ret)
+	if err != nil {
+		return nil, fmt.Errorf("script %v: %v", p.path, err)
+	}
+
+	// keep our running turn total for the next roll
+	for _, o := range options {
+		if o.ID == choice.TakeOptionID {
+			p.sync.Lock()
+			state.turnPoints += o.Points
+			p.sync.Unlock()
+			break
+		}
+	}
+
+	return choice, nil
+}
+
+func (p *ScriptPlayer) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	return nil
+}
+
+// reload compiles the script if it's new or has changed since the last load
+func (p *ScriptPlayer) reload() error {
+	fi, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("script %v: %v", p.path, err)
+	}
+
+	p.sync.RLock()
+	unchanged := p.choose != nil && fi.ModTime().Equal(p.modTime)
+	p.sync.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	thread, done := p.thread()
+	globals, err := starlark.ExecFile(thread, p.path, nil, p.options)
+	if err = done(err); err != nil {
+		return fmt.Errorf("script %v: %w", p.path, err)
+	}
+
+	choose, ok := globals["choose"].(starlark.Callable)
+	if !ok {
+		return fmt.Errorf("script %v: missing choose(dice, options, state) function", p.path)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(p.path), filepath.Ext(p.path))
+	if n, ok := globals["name"].(starlark.String); ok {
+		name = string(n)
+	}
+
+	// globals are frozen after ExecFile, so choose is safe to call concurrently
+	p.sync.Lock()
+	p.choose = choose
+	p.name = name
+	p.modTime = fi.ModTime()
+	p.sync.Unlock()
+
+	return nil
+}
+
+// thread is a thread for one call into the script, canceled if it runs out
+// of steps or time. Call done with the call's error when it returns, it
+// returns the error to report, a scriptTimeout if the time ran out.
+func (p *ScriptPlayer) thread() (thread *starlark.Thread, done func(err error) error) {
+	p.sync.RLock()
+	timeout := p.timeout
+	p.sync.RUnlock()
+
+	thread = &starlark.Thread{Name: p.path}
+	thread.SetMaxExecutionSteps(maxSteps)
+	if timeout <= 0 {
+		return thread, func(err error) error { return err }
+	}
+	var timedOut atomic.Bool
+	t := time.AfterFunc(timeout, func() {
+		timedOut.Store(true)
+		thread.Cancel(fmt.Sprintf("no answer within %v", timeout))
+	})
+	return thread, func(err error) error {
+		t.Stop()
+		if err != nil && timedOut.Load() {
+			return scriptTimeout{timeout}
+		}
+		return err
+	}
+}
+
+// scriptTimeout is a net.Error so it's counted with the HTTP bot timeouts
+type scriptTimeout struct {
+	d time.Duration
+}
+
+func (e scriptTimeout) Error() string   { return fmt.Sprintf("no answer within %v", e.d) }
+func (e scriptTimeout) Timeout() bool   { return true }
+func (e scriptTimeout) Temporary() bool { return true }
+
+func (p *ScriptPlayer) stateDict(state *gameState) *starlark.Dict {
+	p.sync.RLock()
+	defer p.sync.RUnlock()
+
+	scores := starlark.NewDict(len(state.scores))
+	high := 0
+	for i, s := range state.scores {
+		scores.SetKey(starlark.MakeInt(i), starlark.MakeInt(s))
+		if s > high {
+			high = s
+		}
+	}
+
+	d := starlark.NewDict(6)
+	d.SetKey(starlark.String("turn_points"), starlark.MakeInt(state.turnPoints))
+	d.SetKey(starlark.String("score"), starlark.MakeInt(state.startPoints))
+	d.SetKey(starlark.String("scores"), scores)
+	d.SetKey(starlark.String("high_score"), starlark.MakeInt(high))
+	d.SetKey(starlark.String("target_score"), starlark.MakeInt(state.match.targetScore))
+	d.SetKey(starlark.String("is_final_round"), starlark.Bool(state.isFinalRound))
+	return d
+}
+
+func (p *ScriptPlayer) getState(matchID, gameID string) *gameState {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	m, ok := p.data[matchID]
+	if !ok {
+		// we never saw MatchStart, play along with what we know
+		m = &matchState{games: make(map[string]*gameState)}
+		p.data[matchID] = m
+	}
+
+	g, ok := m.games[gameID]
+	if !ok {
+		g = &gameState{match: m, scores: make(map[int]int)}
+		m.games[gameID] = g
+	}
+
+	return g
+}
+
+// toChoice converts the (option_id, stay) tuple returned by a script
+func toChoice(v starlark.Value) (*squelch.PlayerChoice, error) {
+	t, ok := v.(starlark.Tuple)
+	if !ok || len(t) != 2 {
+		return nil, fmt.Errorf("choose must return (option_id, stay), got %v", v)
+	}
+
+	id, ok := starlark.AsString(t[0])
+	if !ok {
+		return nil, fmt.Errorf("option_id must be a string, got %v", t[0].Type())
+	}
+
+	return &squelch.PlayerChoice{
+		TakeOptionID: id,
+		Stay:         bool(t[1].Truth()),
+	}, nil
+}
+
+type matchState struct {
+	targetScore int
+	games       map[string]*gameState
+}
+
+type gameState struct {
+	match        *matchState
+	startPoints  int
+	turnPoints   int
+	isFinalRound bool
+	scores       map[int]int
+}
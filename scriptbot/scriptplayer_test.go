@@ -0,0 +1,181 @@
+package scriptbot
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func writeScript(t *testing.T, dir, src string) string {
+	path := filepath.Join(dir, "bot.star")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("writing script: %v", err)
+	}
+	return path
+}
+
+func TestScriptPlayer_Choose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scriptbot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := NewScriptPlayer(writeScript(t, dir, `
+name = "Tester"
+def choose(dice, options, state):
+    return (options[-1]["id"], state["turn_points"] + options[-1]["points"] >= 100)
+`))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	info, _ := p.Info()
+	if want, got := "Tester", info.Name; want != got {
+		t.Errorf("Name incorrect, want %v got %v", want, got)
+	}
+
+	opts := []squelch.ScoringOption{{ID: "0", DieValues: "15", Points: 150}, {ID: "1", DieValues: "5", Points: 50}}
+	p.MatchStart("m", 6, 5000, 1, 0, nil, nil)
+	p.TurnStart("m", "g", "1", 0, nil, false, squelch.GameState{})
+
+	c, err := p.Choose("m", "g", "1", "11256", opts, squelch.GameState{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if c.TakeOptionID != "1" || c.Stay {
+		t.Errorf("First choice incorrect, got %+v", c)
+	}
+
+	// the second 50 brings the turn to 100
+	c, err = p.Choose("m", "g", "1", "11256", opts, squelch.GameState{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !c.Stay {
+		t.Errorf("Expected to stay on second choice, got %+v", c)
+	}
+}
+
+func TestScriptPlayer_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scriptbot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeScript(t, dir, `
+name = "Before"
+def choose(dice, options, state):
+    return (options[0]["id"], True)
+`)
+	p, err := NewScriptPlayer(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	writeScript(t, dir, `
+name = "After"
+def choose(dice, options, state):
+    return (options[0]["id"], True)
+`)
+	// make sure the modification time moves even on coarse filesystems
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	if err := p.MatchStart("m", 6, 5000, 1, 0, nil, nil); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	info, _ := p.Info()
+	if want, got := "After", info.Name; want != got {
+		t.Errorf("Name incorrect after reload, want %v got %v", want, got)
+	}
+}
+
+func TestScriptPlayer_MissingChoose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scriptbot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := NewScriptPlayer(writeScript(t, dir, `x = 1`)); err == nil {
+		t.Fatalf("expected error for script without choose")
+	}
+}
+
+func TestScriptPlayer_Options(t *testing.T) {
+	path := writeScript(t, t.TempDir(), `
+name = "Opt" + options.get("t", "")
+def choose(dice, options_, state):
+    return (options_[0]["id"], state["turn_points"] >= int(options.get("t", "0")))
+`)
+
+	p, err := NewScriptPlayerWithOptions(path, map[string]string{"t": "200"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	info, _ := p.Info()
+	if want, got := "Opt200", info.Name; want != got {
+		t.Errorf("Name incorrect, want %v got %v", want, got)
+	}
+
+	// no options is an empty dict
+	p, err = NewScriptPlayer(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	info, _ = p.Info()
+	if want, got := "Opt", info.Name; want != got {
+		t.Errorf("Name incorrect, want %v got %v", want, got)
+	}
+}
+
+func TestScriptPlayer_Timeout(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, `
+def choose(dice, options, state):
+    for i in range(1000000000):
+        pass
+    return (options[0]["id"], True)
+`)
+	p, err := NewScriptPlayer(path)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	p.SetTimeout(50 * time.Millisecond)
+
+	opts := []squelch.ScoringOption{{ID: "0", DieValues: "5", Points: 50}}
+	start := time.Now()
+	_, err = p.Choose("m", "g", "1", "23345", opts, squelch.GameState{})
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() || !strings.Contains(err.Error(), "no answer within") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Script should have been stopped, ran for %v", d)
+	}
+
+	// a hot reload that never finishes fails the match start
+	writeScript(t, dir, `
+def spin():
+    for i in range(1000000000):
+        pass
+spin()
+def choose(dice, options, state):
+    return (options[0]["id"], True)
+`)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if err := p.MatchStart("m", 6, 5000, 1, 0, nil, nil); err == nil || !strings.Contains(err.Error(), "no answer within") {
+		t.Errorf("Expected the reload to time out, got %v", err)
+	}
+}
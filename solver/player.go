@@ -0,0 +1,108 @@
+package solver
+
+import (
+	"sync"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+var _ squelch.Player = &Player{}
+
+// Player plays the solved policy. With more than one opponent it plays
+// against the highest scoring one.
+type Player struct {
+	policy *Policy
+	name   string
+
+	data map[string]*turn
+	sync *sync.Mutex
+}
+
+// NewPlayer makes a player for a solved policy
+func NewPlayer(name string, policy *Policy) *Player {
+	return &Player{
+		policy: policy,
+		name:   name,
+		data:   make(map[string]*turn),
+		sync:   &sync.Mutex{},
+	}
+}
+
+func (p *Player) Info() (*squelch.PlayerInfo, error) {
+	return &squelch.PlayerInfo{Name: p.name}, nil
+}
+
+func (p *Player) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
+	return nil
+}
+
+func (p *Player) MatchEnd(matchID string, winsByBotIndex []int) error {
+	return nil
+}
+
+func (p *Player) GameStart(matchID, gameID string) error {
+	return nil
+}
+
+func (p *Player) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	return nil
+}
+
+func (p *Player) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, game squelch.GameState) error {
+	state := p.getState(matchID, gameID, turnID)
+	state.score = startPoints
+	state.isFinalRound = isFinalRound
+
+	for _, t := range otherPlayerTurns {
+		if t.EndPoints > state.opponentScore {
+			state.opponentScore = t.EndPoints
+		}
+	}
+
+	return nil
+}
+
+func (p *Player) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, game squelch.GameState) (*squelch.PlayerChoice, error) {
+	state := p.getState(matchID, gameID, turnID)
+
+	idx, stay := p.policy.Decide(state.score, state.opponentScore, state.points, len(dieValues), options, state.isFinalRound)
+	state.points += options[idx].Points
+
+	if stay {
+		// done with this turn, forget it
+		p.sync.Lock()
+		delete(p.data, matchID+gameID+turnID)
+		p.sync.Unlock()
+	}
+
+	return &squelch.PlayerChoice{
+		TakeOptionID: options[idx].ID,
+		Stay:         stay,
+	}, nil
+}
+
+func (p *Player) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	p.sync.Lock()
+	delete(p.data, matchID+gameID+turnID)
+	p.sync.Unlock()
+	return nil
+}
+
+func (p *Player) getState(matchID, gameID, turnID string) *turn {
+	key := matchID + gameID + turnID
+	p.sync.Lock()
+	v, ok := p.data[key]
+	if !ok {
+		v = &turn{}
+		p.data[key] = v
+	}
+	p.sync.Unlock()
+	return v
+}
+
+type turn struct {
+	isFinalRound  bool
+	score         int
+	opponentScore int
+	points        int
+}
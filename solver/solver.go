@@ -0,0 +1,354 @@
+// Package solver computes the win-probability-maximizing strategy for the
+// two-player game by value iteration over every (my score, opponent score,
+// turn points, dice remaining) position.
+//
+// All scoring options are multiples of 50 points, so positions are tracked in
+// 50 point units. Turn totals are capped at the target plus Headroom; a player
+// that reaches the cap is assumed to stay.
+package solver
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+const (
+	// unit is the smallest number of points any option scores
+	unit = 50
+	// Headroom is how far past the target score a turn total is tracked
+	Headroom = 1000
+	// epsilon is the convergence threshold for a pair of positions
+	epsilon = 1e-6
+)
+
+// Policy is the solved lookup table for a single target score
+type Policy struct {
+	targetScore int
+	target      int // in units
+	limit       int // largest tracked total, in units
+
+	// r[a][b][tp*6+n-1] is the win probability for the player about to roll
+	// n dice with tp turn points at risk, with score a against opponent b
+	r [][][]float32
+	// reach[need*6+n-1] is the chance of scoring at least need more units in
+	// a single turn starting with n dice
+	reach []float64
+}
+
+// move is a dominant way to take points from a roll
+type move struct {
+	points int // in units
+	left   int // dice left after taking the points, 0 means a rollover
+}
+
+// profile is a group of rolls that offer the same moves
+type profile struct {
+	probability float64
+	moves       []move
+}
+
+// outcomes holds the roll profiles for each dice count
+type outcomes struct {
+	profiles [7][]profile
+	squelch  [7]float64
+}
+
+// Solve computes the optimal policy for the given target score. This can
+// take a while for large targets; save the result with Save.
+func Solve(targetScore int) (*Policy, error) {
+	if targetScore < unit {
+		return nil, errors.New("target score too small")
+	}
+
+	p := newPolicy(targetScore)
+	p.r = make([][][]float32, p.target)
+	for a := range p.r {
+		p.r[a] = make([][]float32, p.target)
+		for b := range p.r[a] {
+			p.r[a][b] = make([]float32, (p.limit-a+1)*6)
+		}
+	}
+
+	o := buildOutcomes()
+	p.solveReach(o)
+
+	// positions only ever move to a higher score sum except on a squelch,
+	// which swaps the players, so solve each sum level from the top down and
+	// iterate each pair of swapped positions to a fixed point
+	workers := runtime.NumCPU()
+	for sum := 2 * (p.target - 1); sum >= 0; sum-- {
+		pairs := make(chan [2]int)
+		wg := sync.WaitGroup{}
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ab := range pairs {
+					p.solvePair(o, ab[0], ab[1])
+				}
+			}()
+		}
+
+		for a := sum / 2; a >= 0; a-- {
+			b := sum - a
+			if b >= p.target {
+				break
+			}
+			pairs <- [2]int{a, b}
+		}
+		close(pairs)
+		wg.Wait()
+	}
+
+	return p, nil
+}
+
+func newPolicy(targetScore int) *Policy {
+	target := (targetScore + unit - 1) / unit
+	return &Policy{
+		targetScore: targetScore,
+		target:      target,
+		limit:       target + Headroom/unit,
+	}
+}
+
+func buildOutcomes() *outcomes {
+	o := &outcomes{}
+
+	for n := 1; n <= 6; n++ {
+		grouped := make(map[[7]int]float64)
+		for _, r := range squelch.Rolls(n) {
+			opts := squelch.Options(r.Dice)
+			if len(opts) == 0 {
+				o.squelch[n] += r.Probability
+				continue
+			}
+
+			// for every number of dice left only the most points matters
+			var best [7]int
+			for _, opt := range opts {
+				left := n - len(opt.DieValues)
+				if pts := opt.Points / unit; pts > best[left] {
+					best[left] = pts
+				}
+			}
+			grouped[best] += r.Probability
+		}
+
+		for best, prob := range grouped {
+			pr := profile{probability: prob}
+			for left, pts := range best {
+				if pts > 0 {
+					pr.moves = append(pr.moves, move{points: pts, left: left})
+				}
+			}
+			o.profiles[n] = append(o.profiles[n], pr)
+		}
+	}
+
+	return o
+}
+
+// solveReach fills in the single turn reach probabilities
+func (p *Policy) solveReach(o *outcomes) {
+	maxNeed := p.limit + 1
+	p.reach = make([]float64, (maxNeed+1)*6)
+
+	for n := 1; n <= 6; n++ {
+		p.reach[n-1] = 1
+	}
+
+	for need := 1; need <= maxNeed; need++ {
+		for n := 1; n <= 6; n++ {
+			v := 0.0
+			for _, pr := range o.profiles[n] {
+				best := 0.0
+				for _, m := range pr.moves {
+					mv := 1.0
+					if m.points < need {
+						mv = p.Reach(rolloverDice(m.left), need-m.points)
+					}
+					best = math.Max(best, mv)
+				}
+				v += pr.probability * best
+			}
+			p.reach[need*6+n-1] = v
+		}
+	}
+}
+
+func (p *Policy) solvePair(o *outcomes, a, b int) {
+	// a fair starting guess for both positions
+	wab, wba := 0.5, 0.5
+	for i := 0; i < 1000; i++ {
+		nab := p.solveTurn(o, a, b, 1-wba)
+		nba := nab
+		if a != b {
+			nba = p.solveTurn(o, b, a, 1-nab)
+		}
+
+		done := math.Abs(nab-wab) < epsilon && math.Abs(nba-wba) < epsilon
+		wab, wba = nab, nba
+		if done {
+			break
+		}
+	}
+}
+
+// solveTurn fills in the turn table for score a against b, where squelching
+// is worth squelchValue, and returns the win probability at the turn start
+func (p *Policy) solveTurn(o *outcomes, a, b int, squelchValue float64) float64 {
+	t := p.r[a][b]
+	for tp := p.limit - a; tp >= 0; tp-- {
+		for n := 1; n <= 6; n++ {
+			v := o.squelch[n] * squelchValue
+			for _, pr := range o.profiles[n] {
+				best := 0.0
+				for _, m := range pr.moves {
+					best = math.Max(best, p.after(a, b, tp+m.points, rolloverDice(m.left)))
+				}
+				v += pr.probability * best
+			}
+			t[tp*6+n-1] = float32(v)
+		}
+	}
+
+	return float64(t[5])
+}
+
+// after is the value of having tp turn points with n dice left to roll,
+// picking the better of staying or rolling again
+func (p *Policy) after(a, b, tp, n int) float64 {
+	stay := p.stay(a, b, tp)
+	if a+tp >= p.limit {
+		return stay
+	}
+	return math.Max(stay, float64(p.r[a][b][tp*6+n-1]))
+}
+
+// stay is the win probability of banking tp turn points
+func (p *Policy) stay(a, b, tp int) float64 {
+	total := a + tp
+	if total >= p.target {
+		// the opponent gets one final turn to beat us
+		if total > p.limit {
+			total = p.limit
+		}
+		return 1 - p.Reach(6, total-b+1)
+	}
+
+	return 1 - float64(p.r[b][total][5])
+}
+
+// Reach is the chance of scoring at least need units of 50 points in a single
+// turn starting with diceCount dice, playing to maximize that chance.
+func (p *Policy) Reach(diceCount, need int) float64 {
+	if need <= 0 {
+		return 1
+	}
+	return p.reach[need*6+diceCount-1]
+}
+
+// TargetScore is the target score the policy was solved for
+func (p *Policy) TargetScore() int {
+	return p.targetScore
+}
+
+// WinProbability is the chance the player about to start a turn wins, when
+// neither player has reached the target yet.
+func (p *Policy) WinProbability(myScore, opponentScore int) float64 {
+	a, b := myScore/unit, opponentScore/unit
+	if a >= p.target || b >= p.target {
+		return 0
+	}
+	return float64(p.r[a][b][5])
+}
+
+// Decide picks the best option for a roll, and if the player should stay.
+// turnPoints are the points already taken this turn and diceCount is the
+// number of dice that were rolled. In the final round, after the opponent
+// reached the target, the player rolls until passing the opponent.
+func (p *Policy) Decide(myScore, opponentScore, turnPoints, diceCount int, options []squelch.ScoringOption, isFinalRound bool) (idx int, stay bool) {
+	a, b, tp := myScore/unit, opponentScore/unit, turnPoints/unit
+	if a >= p.target {
+		a = p.target - 1
+	}
+	if b >= p.target {
+		b = p.target - 1
+	}
+
+	bestVal := -1.0
+	for i, o := range options {
+		pts := tp + o.Points/unit
+		n := rolloverDice(diceCount - len(o.DieValues))
+
+		var v float64
+		var s bool
+		if isFinalRound {
+			// all that matters is getting past the opponent this turn
+			need := opponentScore/unit - (a + pts) + 1
+			v, s = p.Reach(n, need), need <= 0
+		} else {
+			stayV := p.stay(a, b, pts)
+			v, s = stayV, true
+			if a+pts < p.limit {
+				if rollV := float64(p.r[a][b][pts*6+n-1]); rollV > stayV {
+					v, s = rollV, false
+				}
+			}
+		}
+
+		if v > bestVal {
+			bestVal, idx, stay = v, i, s
+		}
+	}
+
+	return idx, stay
+}
+
+func rolloverDice(left int) int {
+	if left == 0 {
+		return 6
+	}
+	return left
+}
+
+// policyFile is the serialized form of a Policy
+type policyFile struct {
+	TargetScore int
+	Limit       int
+	R           [][][]float32
+	Reach       []float64
+}
+
+// Save writes the policy lookup table to w
+func (p *Policy) Save(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(policyFile{
+		TargetScore: p.targetScore,
+		Limit:       p.limit,
+		R:           p.r,
+		Reach:       p.reach,
+	})
+}
+
+// LoadPolicy reads a policy lookup table written by Save
+func LoadPolicy(r io.Reader) (*Policy, error) {
+	f := policyFile{}
+	if err := gob.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+
+	p := newPolicy(f.TargetScore)
+	if p.limit != f.Limit || len(f.R) != p.target {
+		return nil, errors.New("policy table doesn't match its target score")
+	}
+	p.r, p.reach = f.R, f.Reach
+
+	return p, nil
+}
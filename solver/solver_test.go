@@ -0,0 +1,82 @@
+package solver
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func TestSolve_Reach(t *testing.T) {
+	p, err := Solve(500)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// scoring anything at all with six dice is just not squelching
+	squelchProb := 0.0
+	for _, r := range squelch.Rolls(6) {
+		if len(squelch.Options(r.Dice)) == 0 {
+			squelchProb += r.Probability
+		}
+	}
+	if want, got := 1-squelchProb, p.Reach(6, 1); math.Abs(want-got) > 1e-9 {
+		t.Errorf("Reach incorrect, want %v got %v", want, got)
+	}
+
+	if p.Reach(6, 2) > p.Reach(6, 1) || p.Reach(1, 1) > p.Reach(6, 1) {
+		t.Errorf("Reach should fall with more points needed or fewer dice")
+	}
+}
+
+func TestSolve_WinProbability(t *testing.T) {
+	p, err := Solve(500)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	start := p.WinProbability(0, 0)
+	if start < 0.5 || start > 1 {
+		t.Errorf("going first should be an advantage, got %v", start)
+	}
+	if ahead, behind := p.WinProbability(400, 0), p.WinProbability(0, 400); ahead <= behind {
+		t.Errorf("being ahead should be better, got ahead %v behind %v", ahead, behind)
+	}
+
+	// a saved table plays the same
+	b := &bytes.Buffer{}
+	if err := p.Save(b); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	l, err := LoadPolicy(b)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := start, l.WinProbability(0, 0); want != got {
+		t.Errorf("Loaded policy incorrect, want %v got %v", want, got)
+	}
+}
+
+func TestPlayer_BeatsLocalBot(t *testing.T) {
+	p, err := Solve(1000)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	rand.Seed(1)
+	tn := squelch.NewTournament(400, 2, 1000, []squelch.Player{
+		NewPlayer("Solver", p),
+		localbot.NewLocalBotPlayer("Local"),
+	})
+
+	r, err := tn.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := "Solver", r.EntrantNames[r.Points[0].EntrantIndex]; want != got {
+		t.Errorf("Winner incorrect, want %v got %v (%+v)", want, got, r.Points)
+	}
+}
@@ -0,0 +1,186 @@
+package squelch
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers used to sign requests between the arena and remote bots
+const (
+	TimestampHeader = "X-Squelch-Timestamp"
+	NonceHeader     = "X-Squelch-Nonce"
+	SignatureHeader = "X-Squelch-Signature"
+)
+
+// ApiAuth holds the per-bot authentication settings for an ApiPlayer.
+// Every field is optional.
+type ApiAuth struct {
+	// Secret is the shared HMAC key. When set, every request is signed and
+	// every response must carry a valid signature.
+	Secret string `json:"secret"`
+	// BearerToken is sent in the Authorization header of every request
+	BearerToken string `json:"bearerToken"`
+	// CertFile and KeyFile are the client certificate for mTLS
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// CAFile verifies the bot's server certificate instead of the system roots
+	CAFile string `json:"caFile"`
+}
+
+func (a ApiAuth) tlsConfig() (*tls.Config, error) {
+	if a.CertFile == "" && a.KeyFile == "" && a.CAFile == "" {
+		return nil, nil
+	}
+
+	c := &tls.Config{}
+
+	if a.CertFile != "" || a.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	if a.CAFile != "" {
+		pem, err := ioutil.ReadFile(a.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %v", a.CAFile)
+		}
+	}
+
+	return c, nil
+}
+
+// sign adds the auth headers to an outbound request and returns the nonce used
+func (a ApiAuth) sign(r *http.Request, body []byte) (string, error) {
+	if a.BearerToken != "" {
+		r.Header.Set("Authorization", "Bearer "+a.BearerToken)
+	}
+
+	if a.Secret == "" {
+		return "", nil
+	}
+
+	n := make([]byte, 16)
+	if _, err := rand.Read(n); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(n)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	r.Header.Set(TimestampHeader, ts)
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set(SignatureHeader, RequestSignature([]byte(a.Secret), r.Method, r.URL.Path, ts, nonce, body))
+
+	return nonce, nil
+}
+
+// verifyResponse confirms the bot signed its reply to our nonce
+func (a ApiAuth) verifyResponse(r *http.Response, nonce string, body []byte) error {
+	if a.Secret == "" {
+		return nil
+	}
+
+	want := ResponseSignature([]byte(a.Secret), nonce, body)
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get(SignatureHeader))) {
+		return errors.New("invalid response signature")
+	}
+
+	return nil
+}
+
+// RequestSignature is the hex HMAC-SHA256 the arena sends in SignatureHeader.
+// It covers the method, path, timestamp, nonce and body of the request.
+func RequestSignature(secret []byte, method, path, timestamp, nonce string, body []byte) string {
+	m := hmac.New(sha256.New, secret)
+	fmt.Fprintf(m, "%s\n%s\n%s\n%s\n", method, path, timestamp, nonce)
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// ResponseSignature is the hex HMAC-SHA256 a bot sends back in SignatureHeader.
+// It covers the request's nonce and the response body, so a reply can't be
+// replayed for a different request.
+func ResponseSignature(secret []byte, nonce string, body []byte) string {
+	m := hmac.New(sha256.New, secret)
+	fmt.Fprintf(m, "%s\n", nonce)
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// RequestVerifier lets a Go bot check that a request really came from the
+// arena: the signature must match, the timestamp must be recent and the
+// nonce must not have been seen before.
+type RequestVerifier struct {
+	secret  []byte
+	maxSkew time.Duration
+
+	sync   sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewRequestVerifier makes a verifier for the shared secret that accepts
+// timestamps within maxSkew of the local clock.
+func NewRequestVerifier(secret string, maxSkew time.Duration) *RequestVerifier {
+	return &RequestVerifier{
+		secret:  []byte(secret),
+		maxSkew: maxSkew,
+		nonces:  make(map[string]time.Time),
+	}
+}
+
+// Verify checks the signature headers on r against its already-read body
+// and returns the nonce to use with ResponseSignature.
+func (v *RequestVerifier) Verify(r *http.Request, body []byte) (string, error) {
+	ts := r.Header.Get(TimestampHeader)
+	nonce := r.Header.Get(NonceHeader)
+	if ts == "" || nonce == "" {
+		return "", errors.New("missing signature headers")
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp: %v", err)
+	}
+	now := time.Now()
+	if d := now.Sub(time.Unix(sec, 0)); d > v.maxSkew || d < -v.maxSkew {
+		return "", errors.New("timestamp outside allowed skew")
+	}
+
+	want := RequestSignature(v.secret, r.Method, r.URL.Path, ts, nonce, body)
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get(SignatureHeader))) {
+		return "", errors.New("invalid signature")
+	}
+
+	v.sync.Lock()
+	defer v.sync.Unlock()
+
+	// forget nonces old enough that their timestamp would be rejected anyway
+	for n, seen := range v.nonces {
+		if now.Sub(seen) > 2*v.maxSkew {
+			delete(v.nonces, n)
+		}
+	}
+	if _, ok := v.nonces[nonce]; ok {
+		return "", errors.New("replayed nonce")
+	}
+	v.nonces[nonce] = now
+
+	return nonce, nil
+}
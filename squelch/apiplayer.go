@@ -1,48 +1,267 @@
 package squelch
 
-import "net/url"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
 
 var _ Player = &ApiPlayer{}
 
+// ApiPlayer is a player backed by a remote bot. Every callback is a JSON POST
+// to the bot's base URL with the callback name appended, e.g. /choose. In
+// decide mode only /decide and, if the bot has it, /info are called.
 type ApiPlayer struct {
+	jsonPlayer
 	baseURL url.URL
+	client  *http.Client
+	auth    ApiAuth
 }
 
 func NewApiPlayer(baseURL url.URL) *ApiPlayer {
-	return &ApiPlayer{
+	p := &ApiPlayer{
 		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
 	}
+	p.jsonPlayer = jsonPlayer{call: p.call, name: baseURL.Host + baseURL.Path}
+	return p
 }
 
-func (p *ApiPlayer) Info() (*PlayerInfo, error) {
-	// TODO: make API call
-	return nil, nil
+// SetTimeout sets how long the bot has to answer each callback, 10 seconds
+// by default
+func (p *ApiPlayer) SetTimeout(d time.Duration) {
+	p.client.Timeout = d
 }
 
-func (p *ApiPlayer) MatchStart(matchId string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string) error {
-	return nil
+// NewApiPlayerWithAuth makes an ApiPlayer that signs its requests and
+// authenticates itself with the given settings.
+func NewApiPlayerWithAuth(baseURL url.URL, auth ApiAuth) (*ApiPlayer, error) {
+	p := NewApiPlayer(baseURL)
+	p.auth = auth
+
+	tlsConfig, err := auth.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("auth for %v: %v", baseURL.String(), err)
+	}
+	if tlsConfig != nil {
+		// keep the default proxy, timeouts and connection limits
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = tlsConfig
+		p.client.Transport = t
+	}
+
+	return p, nil
 }
 
-func (p *ApiPlayer) MatchEnd(matchId string, winsByBotIndex []int) error {
-	return nil
+// jsonPlayer speaks the bot protocol, each callback is a JSON request named
+// like "choose". How the request gets to the bot is up to call.
+type jsonPlayer struct {
+	call func(callback string, req, resp interface{}) error
+	// decide is set for decide mode
+	decide bool
+	// name is the player's name in decide mode if the bot has no info
+	name string
 }
 
-func (p *ApiPlayer) GameStart(matchId, gameId string) error {
-	return nil
+// SetDecideMode switches the bot to the stateless protocol. Instead of the
+// match, game and turn callbacks the bot gets a single "decide" request for
+// each choice with everything needed to make it:
+//
+//	{"state": {...}, "dieValues": "11256", "options": [...]}
+//
+// and answers it like "choose". The request has no ids, so the same situation
+// is always the same request and the answer can be cached. The "info"
+// callback is optional.
+func (p *jsonPlayer) SetDecideMode(on bool) {
+	p.decide = on
 }
 
-func (p *ApiPlayer) GameEnd(matchId, gameId string, finalPlayerTurns []PlayerTurn, winnerBotIndex int) error {
-	return nil
+func (p jsonPlayer) Info() (*PlayerInfo, error) {
+	info := &PlayerInfo{}
+	if err := p.call("info", struct{}{}, info); err != nil {
+		// a bot that's up but has no info is fine in decide mode
+		var refused refusedError
+		if p.decide && errors.As(err, &refused) {
+			return &PlayerInfo{Name: p.name}, nil
+		}
+		return nil, err
+	}
+	return info, nil
 }
 
-func (p *ApiPlayer) TurnStart(matchId, gameId, turnId string, startPoints int, otherPlayerTurns []PlayerTurn, isFinalRound bool) error {
-	return nil
+// refusedError is a callback the bot answered but turned down, a 404 from an
+// HTTP bot or an error answer from a process bot, rather than one that failed
+type refusedError struct {
+	msg string
+}
+
+func (e refusedError) Error() string { return e.msg }
+
+func (p jsonPlayer) MatchStart(matchId string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []Handicap) error {
+	if p.decide {
+		return nil
+	}
+	return p.call("matchstart", struct {
+		MatchID      string     `json:"matchId"`
+		DieCount     int        `json:"dieCount"`
+		MaxPoints    int        `json:"maxPoints"`
+		GameCount    int        `json:"gameCount"`
+		YourBotIndex int        `json:"yourBotIndex"`
+		BotNames     []string   `json:"botNames"`
+		Handicaps    []Handicap `json:"handicaps,omitempty"`
+	}{matchId, dieCount, maxPoints, gameCount, yourBotIndex, botNames, handicaps}, nil)
 }
 
-func (p *ApiPlayer) Choose(matchId, gameId, turnId string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
-	return nil, nil
+func (p jsonPlayer) MatchEnd(matchId string, winsByBotIndex []int) error {
+	if p.decide {
+		return nil
+	}
+	return p.call("matchend", struct {
+		MatchID        string `json:"matchId"`
+		WinsByBotIndex []int  `json:"winsByBotIndex"`
+	}{matchId, winsByBotIndex}, nil)
+}
+
+func (p jsonPlayer) GameStart(matchId, gameId string) error {
+	if p.decide {
+		return nil
+	}
+	return p.call("gamestart", struct {
+		MatchID string `json:"matchId"`
+		GameID  string `json:"gameId"`
+	}{matchId, gameId}, nil)
+}
+
+func (p jsonPlayer) GameEnd(matchId, gameId string, finalPlayerTurns []PlayerTurn, winnerBotIndex int) error {
+	if p.decide {
+		return nil
+	}
+	return p.call("gameend", struct {
+		MatchID          string       `json:"matchId"`
+		GameID           string       `json:"gameId"`
+		FinalPlayerTurns []PlayerTurn `json:"finalPlayerTurns"`
+		WinnerBotIndex   int          `json:"winnerBotIndex"`
+	}{matchId, gameId, finalPlayerTurns, winnerBotIndex}, nil)
 }
 
-func (p *ApiPlayer) Squelch(matchId, gameId, turnId string, dieValues string) error {
+func (p jsonPlayer) TurnStart(matchId, gameId, turnId string, startPoints int, otherPlayerTurns []PlayerTurn, isFinalRound bool, state GameState) error {
+	if p.decide {
+		return nil
+	}
+	return p.call("turnstart", struct {
+		MatchID          string       `json:"matchId"`
+		GameID           string       `json:"gameId"`
+		TurnID           string       `json:"turnId"`
+		StartPoints      int          `json:"startPoints"`
+		OtherPlayerTurns []PlayerTurn `json:"otherPlayerTurns"`
+		IsFinalRound     bool         `json:"isFinalRound"`
+		State            GameState    `json:"state"`
+	}{matchId, gameId, turnId, startPoints, otherPlayerTurns, isFinalRound, state}, nil)
+}
+
+func (p jsonPlayer) Choose(matchId, gameId, turnId string, dieValues string, options []ScoringOption, state GameState) (*PlayerChoice, error) {
+	if p.decide {
+		return p.decideChoice(dieValues, options, state)
+	}
+
+	choice := &PlayerChoice{}
+	err := p.call("choose", struct {
+		MatchID   string          `json:"matchId"`
+		GameID    string          `json:"gameId"`
+		TurnID    string          `json:"turnId"`
+		DieValues string          `json:"dieValues"`
+		Options   []ScoringOption `json:"options"`
+		State     GameState       `json:"state"`
+	}{matchId, gameId, turnId, dieValues, options, state}, choice)
+	if err != nil {
+		return nil, err
+	}
+	return choice, nil
+}
+
+// decideChoice asks a decide mode bot for its choice
+func (p jsonPlayer) decideChoice(dieValues string, options []ScoringOption, state GameState) (*PlayerChoice, error) {
+	choice := &PlayerChoice{}
+	err := p.call("decide", struct {
+		State     GameState       `json:"state"`
+		DieValues string          `json:"dieValues"`
+		Options   []ScoringOption `json:"options"`
+	}{state, dieValues, options}, choice)
+	if err != nil {
+		return nil, err
+	}
+	return choice, nil
+}
+
+func (p jsonPlayer) Squelch(matchId, gameId, turnId string, dieValues string) error {
+	if p.decide {
+		return nil
+	}
+	return p.call("squelch", struct {
+		MatchID   string `json:"matchId"`
+		GameID    string `json:"gameId"`
+		TurnID    string `json:"turnId"`
+		DieValues string `json:"dieValues"`
+	}{matchId, gameId, turnId, dieValues}, nil)
+}
+
+// call posts the request body as JSON to the named callback endpoint and
+// decodes the bot's reply into resp, if given.
+func (p *ApiPlayer) call(endpoint string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	u := p.baseURL
+	u.Path = path.Join(u.Path, endpoint)
+
+	httpReq, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	nonce, err := p.auth.sign(httpReq, body)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("%v: %w", endpoint, err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return fmt.Errorf("%v: reading response: %w", endpoint, err)
+	}
+
+	if httpResp.StatusCode == http.StatusNotFound {
+		return refusedError{fmt.Sprintf("%v: bot returned status %v", endpoint, httpResp.Status)}
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return fmt.Errorf("%v: bot returned status %v", endpoint, httpResp.Status)
+	}
+
+	if err := p.auth.verifyResponse(httpResp, nonce, respBody); err != nil {
+		return fmt.Errorf("%v: %v", endpoint, err)
+	}
+
+	if resp == nil || len(respBody) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		return fmt.Errorf("%v: invalid response: %v", endpoint, err)
+	}
+
 	return nil
 }
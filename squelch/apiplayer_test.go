@@ -0,0 +1,221 @@
+package squelch
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func getSignedBotServer(t *testing.T, secret string, respSecret string) *httptest.Server {
+	v := NewRequestVerifier(secret, time.Minute)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		nonce, err := v.Verify(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if want, got := "Bearer tok", r.Header.Get("Authorization"); want != got {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+
+		var resp interface{} = struct{}{}
+		switch r.URL.Path {
+		case "/bot/info":
+			resp = PlayerInfo{Name: "Remote"}
+		case "/bot/choose":
+			req := struct {
+				Options []ScoringOption `json:"options"`
+			}{}
+			json.Unmarshal(body, &req)
+			resp = PlayerChoice{TakeOptionID: req.Options[0].ID, Stay: true}
+		}
+
+		out, _ := json.Marshal(resp)
+		w.Header().Set(SignatureHeader, ResponseSignature([]byte(respSecret), nonce, out))
+		w.Write(out)
+	}))
+}
+
+func TestApiPlayer_Signed(t *testing.T) {
+	s := getSignedBotServer(t, "shh", "shh")
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL + "/bot")
+	p, err := NewApiPlayerWithAuth(*u, ApiAuth{Secret: "shh", BearerToken: "tok"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	info, err := p.Info()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := "Remote", info.Name; want != got {
+		t.Errorf("Name incorrect, want %v got %v", want, got)
+	}
+
+	c, err := p.Choose("m", "g", "1", "11256", getDiceOptions(0, "11256"), GameState{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := "0", c.TakeOptionID; want != got || !c.Stay {
+		t.Errorf("Choice incorrect, want %v got %+v", want, c)
+	}
+}
+
+func TestApiPlayer_WrongSecret(t *testing.T) {
+	s := getSignedBotServer(t, "shh", "shh")
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL + "/bot")
+	p, _ := NewApiPlayerWithAuth(*u, ApiAuth{Secret: "nope", BearerToken: "tok"})
+
+	if _, err := p.Info(); err == nil {
+		t.Fatalf("expected an error with the wrong secret")
+	}
+}
+
+func TestApiPlayer_ImpersonatedBot(t *testing.T) {
+	// the bot doesn't know the secret, so its responses can't be trusted
+	s := getSignedBotServer(t, "shh", "guess")
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL + "/bot")
+	p, _ := NewApiPlayerWithAuth(*u, ApiAuth{Secret: "shh", BearerToken: "tok"})
+
+	if _, err := p.Info(); err == nil {
+		t.Fatalf("expected an error for an unsigned response")
+	}
+}
+
+func TestRequestVerifier_Replay(t *testing.T) {
+	body := []byte("{}")
+	r := httptest.NewRequest(http.MethodPost, "/choose", nil)
+	a := ApiAuth{Secret: "shh"}
+	if _, err := a.sign(r, body); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	v := NewRequestVerifier("shh", time.Minute)
+	if _, err := v.Verify(r, body); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := v.Verify(r, body); err == nil {
+		t.Fatalf("expected replayed request to fail")
+	}
+}
+
+func TestApiPlayer_DecideMode(t *testing.T) {
+	var paths []string
+	var last struct {
+		State     GameState       `json:"state"`
+		DieValues string          `json:"dieValues"`
+		Options   []ScoringOption `json:"options"`
+	}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/bot/decide" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&last)
+		// take the first option until the turn is worth 200
+		json.NewEncoder(w).Encode(PlayerChoice{TakeOptionID: last.Options[0].ID, Stay: last.State.TurnPoints+last.Options[0].Points >= 200})
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL + "/bot")
+	p := NewApiPlayer(*u)
+	p.SetDecideMode(true)
+
+	// without an info callback the bot is named for its URL
+	info, err := p.Info()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := u.Host+"/bot", info.Name; want != got {
+		t.Errorf("Name incorrect, want %v got %v", want, got)
+	}
+
+	g := NewGame([]Player{p, stayPlayer("b")}, 2000, "m", "g", 0)
+	g.roll = getRollFunc(t, []string{"122346", "12234", "111111", "223466"})
+	if _, err := g.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	decides := 0
+	for _, p := range paths {
+		switch p {
+		case "/bot/decide":
+			decides++
+		case "/bot/info":
+		default:
+			t.Errorf("Only info and decide should be called, got %v", p)
+		}
+	}
+	if decides != 2 {
+		t.Errorf("Decide count incorrect, want 2 got %v", decides)
+	}
+
+	// the second roll of the turn comes with the first
+	if last.DieValues != "12234" || last.State.TurnPoints != 100 || len(last.State.Rolls) != 1 || last.State.Rolls[0].Take != "1" {
+		t.Errorf("Decide request incorrect: %+v", last)
+	}
+}
+
+func TestApiPlayer_DecideModeInfoErrors(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	u, _ := url.Parse(s.URL)
+
+	// a failing bot isn't mistaken for one without info
+	p := NewApiPlayer(*u)
+	p.SetDecideMode(true)
+	if _, err := p.Info(); err == nil {
+		t.Errorf("Expected an error from a bot answering 503")
+	}
+
+	s.Close()
+	if _, err := p.Info(); err == nil {
+		t.Errorf("Expected an error from a bot that isn't there")
+	}
+}
+
+func TestNewApiPlayerWithAuth_CAFile(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(PlayerInfo{Name: "tls"})
+	}))
+	defer s.Close()
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	u, _ := url.Parse(s.URL)
+	p, err := NewApiPlayerWithAuth(*u, ApiAuth{CAFile: ca})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	info, err := p.Info()
+	if err != nil || info.Name != "tls" {
+		t.Fatalf("Info incorrect, got %+v %v", info, err)
+	}
+
+	// the default transport's settings are kept
+	tr := p.client.Transport.(*http.Transport)
+	if tr.Proxy == nil || tr.TLSHandshakeTimeout == 0 || tr.IdleConnTimeout == 0 {
+		t.Errorf("Transport lost the defaults: %+v", tr)
+	}
+}
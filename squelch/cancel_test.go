@@ -0,0 +1,133 @@
+package squelch
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTournament_Cancel(t *testing.T) {
+	slow := func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		time.Sleep(time.Millisecond)
+		return &PlayerChoice{options[0].ID, true}, nil
+	}
+	fast := func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		return &PlayerChoice{options[0].ID, true}, nil
+	}
+	tr := NewTournament(1000, 2, 500, []Player{
+		getMockPlayerAnyCallback("slow", slow),
+		getMockPlayerAnyCallback("a", fast),
+		getMockPlayerAnyCallback("b", fast),
+	})
+	buf := &checkpointBuffer{}
+	tr.SetCheckpoint(buf)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	r, err := tr.RunContext(ctx)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("took too long to cancel: %v", time.Since(start))
+	}
+
+	if !r.Incomplete {
+		t.Errorf("expected incomplete results")
+	}
+	if r.MatchesFinished >= tr.GetMatchCount() {
+		t.Errorf("Finished matches incorrect, got %v", r.MatchesFinished)
+	}
+
+	// only whole matches count
+	finished := 0
+	for _, p := range r.Points {
+		finished += p.Matches
+		if p.TotalWins > p.Matches*1000 {
+			t.Errorf("Partial match counted: %+v", p)
+		}
+	}
+	if finished != 2*r.MatchesFinished {
+		t.Errorf("Match counts incorrect, got %v for %v matches", finished, r.MatchesFinished)
+	}
+	if buf.lines != r.MatchesFinished {
+		t.Errorf("Checkpointed %v matches, want %v", buf.lines, r.MatchesFinished)
+	}
+}
+
+func TestTournament_CancelGrace(t *testing.T) {
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	block := func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		<-stuck
+		return &PlayerChoice{options[0].ID, true}, nil
+	}
+	tr := NewTournament(1, 2, 500, []Player{
+		getMockPlayerAnyCallback("a", block),
+		getMockPlayerAnyCallback("b", block),
+	})
+	tr.SetGracePeriod(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	r, err := tr.RunContext(ctx)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !r.Incomplete || r.MatchesFinished != 0 {
+		t.Errorf("Results incorrect, got %+v", r)
+	}
+}
+
+func TestTournament_CancelGraceStopsGames(t *testing.T) {
+	var calls int64
+	slow := func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(5 * time.Millisecond)
+		return &PlayerChoice{options[0].ID, true}, nil
+	}
+	// a game to 100000 goes on far longer than the grace period
+	tr := NewTournament(1, 2, 100000, []Player{
+		getMockPlayerAnyCallback("a", slow),
+		getMockPlayerAnyCallback("b", slow),
+		getMockPlayerAnyCallback("c", slow),
+	})
+	tr.SetGracePeriod(20 * time.Millisecond)
+	buf := &checkpointBuffer{}
+	tr.SetCheckpoint(buf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if _, err := tr.RunContext(ctx); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	returned := atomic.LoadInt64(&calls)
+
+	// the games given up on stop at their next turn instead of playing on
+	time.Sleep(200 * time.Millisecond)
+	if more := atomic.LoadInt64(&calls) - returned; more > int64(tr.GetMatchCount()) {
+		t.Errorf("Games kept playing after giving up on them, %v more choices", more)
+	}
+	if buf.lines != 0 {
+		t.Errorf("Nothing should be checkpointed, got %v matches", buf.lines)
+	}
+}
+
+// checkpointBuffer counts the checkpointed matches
+type checkpointBuffer struct {
+	lines int
+}
+
+func (b *checkpointBuffer) Write(p []byte) (int, error) {
+	if !bytes.HasPrefix(p, []byte(`{"header"`)) {
+		b.lines++
+	}
+	return len(p), nil
+}
@@ -0,0 +1,225 @@
+package squelch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MatchResult is a finished match as stored in a checkpoint
+type MatchResult struct {
+	// Entrants are the entrant indexes that played, in increasing order
+	Entrants []int `json:"entrants"`
+	// Names are the names of the Entrants, to catch a resume with different bots
+	Names []string `json:"names"`
+	// Wins are the games won by each of the Entrants
+	Wins []int `json:"wins"`
+	// Errors are the games each of the Entrants ended with an error, plus one
+	// if it failed the match start
+	Errors []int `json:"errors,omitempty"`
+}
+
+// CheckpointHeader is the first line of a checkpoint, the rules its matches
+// were played under
+type CheckpointHeader struct {
+	GamesPerMatch   int `json:"gamesPerMatch"`
+	PlayersPerMatch int `json:"playersPerMatch"`
+	TargetScore     int `json:"targetScore"`
+	// Teams are the entrant indexes of each team, nil if there are none
+	Teams [][]int `json:"teams,omitempty"`
+	// Handicaps are each entrant's handicap, nil if there are none
+	Handicaps []Handicap `json:"handicaps,omitempty"`
+}
+
+// same is set if the headers have the same rules
+func (h CheckpointHeader) same(o CheckpointHeader) bool {
+	return reflect.DeepEqual(h, o)
+}
+
+// headerLine wraps the header so it can't be mistaken for a match
+type headerLine struct {
+	Header *CheckpointHeader `json:"header"`
+}
+
+func (r MatchResult) key() string {
+	return matchKey(r.Entrants)
+}
+
+// matchKey identifies a combination of entrants
+func matchKey(entrants []int) string {
+	s := make([]string, len(entrants))
+	for i, e := range entrants {
+		s[i] = strconv.Itoa(e)
+	}
+	return strings.Join(s, ",")
+}
+
+// checkpointWriter appends every finished match to the checkpoint
+type checkpointWriter struct {
+	sync sync.Mutex
+	w    io.Writer
+	err  error
+	// closed is set once the tournament has returned its results
+	closed bool
+}
+
+func (c *checkpointWriter) write(r MatchResult) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	if c.err != nil || c.closed {
+		return
+	}
+
+	b, err := json.Marshal(r)
+	if err == nil {
+		_, err = c.w.Write(append(b, '\n'))
+	}
+	if s, ok := c.w.(interface{ Sync() error }); ok && err == nil {
+		// make sure it survives the crash we're guarding against
+		err = s.Sync()
+	}
+	if err != nil {
+		logger().Error("checkpoint failed", "err", err)
+		c.err = err
+	}
+}
+
+// close refuses any more writes
+func (c *checkpointWriter) close() {
+	c.sync.Lock()
+	c.closed = true
+	c.sync.Unlock()
+}
+
+// SetCheckpoint writes every match result to w as a line of JSON as soon as
+// the match finishes. If w can be synced, e.g. an *os.File, it's synced after
+// every match. The checkpoint starts with the tournament's header and any
+// matches passed to Resume, so call Resume first.
+func (t *Tournament) SetCheckpoint(w io.Writer) error {
+	h := t.CheckpointHeader()
+	b, err := json.Marshal(headerLine{&h})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+
+	t.checkpoint = &checkpointWriter{w: w}
+	for _, r := range t.resumedOrder {
+		t.checkpoint.write(r)
+	}
+	return t.CheckpointErr()
+}
+
+// CheckpointHeader is the header of the tournament's checkpoint
+func (t *Tournament) CheckpointHeader() CheckpointHeader {
+	h := CheckpointHeader{
+		GamesPerMatch:   t.gamesPerMatch,
+		PlayersPerMatch: t.playersPerMatch,
+		TargetScore:     t.targetScore,
+		Teams:           t.teams,
+	}
+	// no handicaps at all is the same as none set
+	for _, hc := range t.handicaps {
+		if hc != (Handicap{}) {
+			h.Handicaps = t.handicaps
+			break
+		}
+	}
+	return h
+}
+
+// Resume skips the matches already played in a previous run and counts
+// their results instead. The entrants must be the same, in the same order, as
+// the run that wrote the checkpoint, and the header must match ours so
+// matches played under other rules aren't mixed in.
+func (t *Tournament) Resume(header CheckpointHeader, done []MatchResult) error {
+	if len(done) > 0 && !header.same(t.CheckpointHeader()) {
+		return fmt.Errorf("checkpoint was played with %+v, not %+v", header, t.CheckpointHeader())
+	}
+
+	t.resumed = make(map[string]MatchResult, len(done))
+	t.resumedOrder = done
+	for _, r := range done {
+		t.resumed[r.key()] = r
+	}
+	return nil
+}
+
+// CheckpointErr returns the first error writing the checkpoint, if any
+func (t *Tournament) CheckpointErr() error {
+	if t.checkpoint == nil {
+		return nil
+	}
+	t.checkpoint.sync.Lock()
+	defer t.checkpoint.sync.Unlock()
+	return t.checkpoint.err
+}
+
+// validateResumed makes sure the resumed matches were played by our entrants
+func (t *Tournament) validateResumed(entrantNames []string) error {
+	for _, r := range t.resumed {
+		if len(r.Entrants) != t.playersPerMatch || len(r.Names) != len(r.Entrants) || len(r.Wins) != len(r.Entrants) {
+			return fmt.Errorf("checkpoint match %v doesn't have %v players", r.key(), t.playersPerMatch)
+		}
+		for i, e := range r.Entrants {
+			if e < 0 || e >= len(entrantNames) || entrantNames[e] != r.Names[i] {
+				return fmt.Errorf("checkpoint match %v was played by different bots %v", r.key(), r.Names)
+			}
+		}
+	}
+	return nil
+}
+
+// ReadCheckpoint reads the header and match results of a checkpoint. A
+// partly written last line, from a crash mid-write, is ignored.
+func ReadCheckpoint(r io.Reader) (CheckpointHeader, []MatchResult, error) {
+	var header *CheckpointHeader
+	var res []MatchResult
+	var bad error
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		if bad != nil {
+			// only the last line may be broken
+			return CheckpointHeader{}, nil, bad
+		}
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+
+		if header == nil {
+			var h headerLine
+			if err := json.Unmarshal(sc.Bytes(), &h); err != nil {
+				bad = fmt.Errorf("checkpoint line %v: %v", line, err)
+				continue
+			}
+			if h.Header == nil {
+				return CheckpointHeader{}, nil, errors.New("checkpoint has no header, it was written by an older version")
+			}
+			header = h.Header
+			continue
+		}
+
+		var m MatchResult
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			bad = fmt.Errorf("checkpoint line %v: %v", line, err)
+			continue
+		}
+		res = append(res, m)
+	}
+
+	if header == nil {
+		// empty, or the header itself was cut short
+		return CheckpointHeader{}, nil, sc.Err()
+	}
+	return *header, res, sc.Err()
+}
@@ -0,0 +1,179 @@
+package squelch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stayPlayer(name string) *MockPlayer {
+	return getMockPlayerAnyCallback(name, func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		return &PlayerChoice{options[0].ID, true}, nil
+	})
+}
+
+func TestTournament_Checkpoint(t *testing.T) {
+	tr := NewTournament(2, 2, 500, []Player{stayPlayer("a"), stayPlayer("b"), stayPlayer("c")})
+	buf := &bytes.Buffer{}
+	tr.SetCheckpoint(buf)
+
+	if _, err := tr.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := tr.CheckpointErr(); err != nil {
+		t.Fatalf("Checkpoint error: %v", err)
+	}
+
+	header, done, err := ReadCheckpoint(buf)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	assert.Equal(t, CheckpointHeader{GamesPerMatch: 2, PlayersPerMatch: 2, TargetScore: 500}, header)
+	if len(done) != 3 {
+		t.Fatalf("Checkpoint match count incorrect, want 3 got %v", len(done))
+	}
+
+	names := []string{"a", "b", "c"}
+	for _, r := range done {
+		if r.Entrants[0] >= r.Entrants[1] {
+			t.Errorf("Entrants out of order: %v", r.Entrants)
+		}
+		for i, e := range r.Entrants {
+			if r.Names[i] != names[e] {
+				t.Errorf("Name incorrect for entrant %v, got %v", e, r.Names[i])
+			}
+		}
+		if r.Wins[0]+r.Wins[1] != 2 {
+			t.Errorf("Wins incorrect, got %v", r.Wins)
+		}
+	}
+}
+
+func TestTournament_Resume(t *testing.T) {
+	a, b, c := stayPlayer("a"), stayPlayer("b"), stayPlayer("c")
+	tr := NewTournament(2, 2, 500, []Player{a, b, c})
+	err := tr.Resume(tr.CheckpointHeader(), []MatchResult{
+		{Entrants: []int{0, 1}, Names: []string{"a", "b"}, Wins: []int{2, 0}},
+		{Entrants: []int{0, 2}, Names: []string{"a", "c"}, Wins: []int{1, 1}},
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := tr.SetCheckpoint(buf); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	r, err := tr.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// only b vs c is left to play
+	a.AssertNumberOfCalls(t, "MatchStart", 0)
+	b.AssertNumberOfCalls(t, "MatchStart", 1)
+	c.AssertNumberOfCalls(t, "MatchStart", 1)
+
+	// the resumed matches are written again ahead of the one played
+	_, done, _ := ReadCheckpoint(buf)
+	if len(done) != 3 || matchKey(done[2].Entrants) != "1,2" {
+		t.Errorf("Checkpoint incorrect, got %+v", done)
+	}
+
+	for _, p := range r.Points {
+		if p.Matches != 2 {
+			t.Errorf("Entrant %v matches incorrect, want 2 got %v", p.EntrantIndex, p.Matches)
+		}
+		if p.EntrantIndex == 0 && (p.Points != 1 || p.TotalWins != 3) {
+			t.Errorf("Resumed results not merged, got %+v", p)
+		}
+	}
+}
+
+func TestTournament_ResumeMismatch(t *testing.T) {
+	tr := NewTournament(2, 2, 500, []Player{stayPlayer("a"), stayPlayer("b")})
+	tr.Resume(tr.CheckpointHeader(), []MatchResult{{Entrants: []int{0, 1}, Names: []string{"a", "x"}, Wins: []int{2, 0}}})
+
+	if _, err := tr.Run(); err == nil {
+		t.Errorf("expected an error for different bots")
+	}
+}
+
+func TestTournament_ResumeOtherRules(t *testing.T) {
+	tr := NewTournament(2, 2, 500, []Player{stayPlayer("a"), stayPlayer("b")})
+	done := []MatchResult{{Entrants: []int{0, 1}, Names: []string{"a", "b"}, Wins: []int{2, 0}}}
+
+	for _, h := range []CheckpointHeader{
+		{GamesPerMatch: 3, PlayersPerMatch: 2, TargetScore: 500},
+		{GamesPerMatch: 2, PlayersPerMatch: 2, TargetScore: 1000},
+		{},
+	} {
+		if err := tr.Resume(h, done); err == nil {
+			t.Errorf("Expected an error resuming %+v", h)
+		}
+	}
+}
+
+func TestTournament_ResumeOtherHandicaps(t *testing.T) {
+	players := []Player{stayPlayer("a"), stayPlayer("b"), stayPlayer("c"), stayPlayer("d")}
+	done := []MatchResult{{Entrants: []int{0, 1, 2, 3}, Names: []string{"a", "b", "c", "d"}, Wins: []int{1, 0, 1, 0}}}
+
+	tr := NewTournament(1, 4, 500, players)
+	tr.SetTeams([][]int{{0, 2}, {1, 3}})
+	tr.SetHandicaps([]Handicap{{StartScore: 100}, {}, {}, {}})
+	header := tr.CheckpointHeader()
+
+	// the header survives being written and read back
+	buf := &bytes.Buffer{}
+	if err := tr.SetCheckpoint(buf); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	read, _, err := ReadCheckpoint(buf)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := tr.Resume(read, done); err != nil {
+		t.Errorf("Error resuming our own header: %v", err)
+	}
+
+	// other handicaps, none, or other teams
+	other := NewTournament(1, 4, 500, players)
+	other.SetTeams([][]int{{0, 2}, {1, 3}})
+	other.SetHandicaps([]Handicap{{StartScore: 200}, {}, {}, {}})
+	none := NewTournament(1, 4, 500, players)
+	none.SetTeams([][]int{{0, 2}, {1, 3}})
+	none.SetHandicaps(make([]Handicap, 4))
+	teams := NewTournament(1, 4, 500, players)
+	teams.SetTeams([][]int{{0, 1}, {2, 3}})
+	teams.SetHandicaps([]Handicap{{StartScore: 100}, {}, {}, {}})
+	for _, o := range []*Tournament{other, none, teams} {
+		if err := o.Resume(header, done); err == nil {
+			t.Errorf("Expected an error resuming %+v into %+v", header, o.CheckpointHeader())
+		}
+	}
+}
+
+func TestReadCheckpoint(t *testing.T) {
+	header := `{"header":{"gamesPerMatch":1,"playersPerMatch":2,"targetScore":500}}` + "\n"
+	good := `{"entrants":[0,1],"names":["a","b"],"wins":[1,0]}` + "\n"
+
+	// a crash mid-write leaves a partial last line
+	h, done, err := ReadCheckpoint(strings.NewReader(header + good + `{"entrants":[0,`))
+	if err != nil || len(done) != 1 || h.TargetScore != 500 {
+		t.Errorf("Partial last line: got %+v, %v, %v", h, done, err)
+	}
+
+	if _, _, err := ReadCheckpoint(strings.NewReader(header + `{"entr` + "\n" + good)); err == nil {
+		t.Errorf("expected an error for a broken line in the middle")
+	}
+
+	if _, _, err := ReadCheckpoint(strings.NewReader(good)); err == nil {
+		t.Errorf("expected an error for a checkpoint without a header")
+	}
+
+	if _, done, err := ReadCheckpoint(strings.NewReader("")); err != nil || len(done) != 0 {
+		t.Errorf("Empty checkpoint: got %v, %v", done, err)
+	}
+}
@@ -0,0 +1,152 @@
+package squelch
+
+// EventType is what happened in a GameEvent
+type EventType string
+
+// the events a match and its games report, in the order they happen
+const (
+	EventMatchStart EventType = "matchstart"
+	EventGameStart  EventType = "gamestart"
+	EventTurnStart  EventType = "turnstart"
+	EventRoll       EventType = "roll"
+	EventChoice     EventType = "choice"
+	EventSquelch    EventType = "squelch"
+	EventRollover   EventType = "rollover"
+	EventReroll     EventType = "reroll"
+	EventOvertime   EventType = "overtime"
+	EventGameEnd    EventType = "gameend"
+	EventMatchEnd   EventType = "matchend"
+)
+
+// GameEvent is a single step of a match as it's played. Every event carries
+// the player names and scores so an observer can join at any point.
+type GameEvent struct {
+	Type    EventType `json:"type"`
+	MatchID string    `json:"matchId"`
+	GameID  string    `json:"gameId,omitempty"`
+	TurnID  string    `json:"turnId,omitempty"`
+	// Players are the player names by bot index
+	Players []string `json:"players"`
+	// Scores are the banked scores by bot index, not set on match events
+	Scores []int `json:"scores,omitempty"`
+	// Teams are the team of each bot index in a team game
+	Teams []int `json:"teams,omitempty"`
+
+	// BotIndex is the player the event is about
+	BotIndex     int  `json:"botIndex"`
+	IsFinalRound bool `json:"isFinalRound"`
+	// TurnPoints are the points taken so far this turn. On a squelch they're
+	// the points lost.
+	TurnPoints int `json:"turnPoints"`
+
+	// Dice and Options are set on rolls and squelches
+	Dice    string          `json:"dice,omitempty"`
+	Options []ScoringOption `json:"options,omitempty"`
+
+	// Take, Points and Stay are set on choices
+	Take   string `json:"take,omitempty"`
+	Points int    `json:"points,omitempty"`
+	Stay   bool   `json:"stay,omitempty"`
+
+	// WinnerIndex is set when the game ends, -1 if it ended with an error
+	WinnerIndex int    `json:"winnerIndex"`
+	Err         string `json:"error,omitempty"`
+	// Wins are the game wins by bot index when the match ends
+	Wins []int `json:"wins,omitempty"`
+}
+
+// Observer watches matches and games as they're played, e.g. to record,
+// display or measure them. Observers are called synchronously, so a slow
+// observer slows down the game, and a Tournament calls them from every match
+// at once so they must be safe for concurrent use.
+type Observer interface {
+	OnMatchStart(e GameEvent)
+	OnGameStart(e GameEvent)
+	OnTurnStart(e GameEvent)
+	OnRoll(e GameEvent)
+	OnChoice(e GameEvent)
+	OnSquelch(e GameEvent)
+	// OnRollover is called when a player has scored with every die and
+	// rolls all 6 again
+	OnRollover(e GameEvent)
+	// OnReroll is called when a handicap reroll saves a player from a
+	// squelch and the same dice are rolled again
+	OnReroll(e GameEvent)
+	// OnOvertime is called when a player reaches the target score and the
+	// final round starts
+	OnOvertime(e GameEvent)
+	OnGameEnd(e GameEvent)
+	OnMatchEnd(e GameEvent)
+}
+
+// EventFunc is an Observer that hands every event to a single func
+type EventFunc func(e GameEvent)
+
+func (f EventFunc) OnMatchStart(e GameEvent) { f(e) }
+func (f EventFunc) OnGameStart(e GameEvent)  { f(e) }
+func (f EventFunc) OnTurnStart(e GameEvent)  { f(e) }
+func (f EventFunc) OnRoll(e GameEvent)       { f(e) }
+func (f EventFunc) OnChoice(e GameEvent)     { f(e) }
+func (f EventFunc) OnSquelch(e GameEvent)    { f(e) }
+func (f EventFunc) OnRollover(e GameEvent)   { f(e) }
+func (f EventFunc) OnReroll(e GameEvent)     { f(e) }
+func (f EventFunc) OnOvertime(e GameEvent)   { f(e) }
+func (f EventFunc) OnGameEnd(e GameEvent)    { f(e) }
+func (f EventFunc) OnMatchEnd(e GameEvent)   { f(e) }
+
+// AddObserver registers an observer for every event of the game
+func (g *Game) AddObserver(o Observer) {
+	g.observers = append(g.observers, o)
+}
+
+// emit reports an event with the match, game and current scores filled in
+func (g *Game) emit(e GameEvent) {
+	if len(g.observers) == 0 {
+		return
+	}
+
+	e.MatchID, e.GameID, e.Teams = g.matchID, g.gameID, g.teams
+	e.Players = make([]string, g.playerCount)
+	e.Scores = make([]int, g.playerCount)
+	r := g.players
+	for i := 0; i < g.playerCount; i++ {
+		p := r.Value.(*gamePlayer)
+		if p.info != nil {
+			e.Players[p.index] = p.info.Name
+		}
+		e.Scores[p.index] = p.score
+		r = r.Next()
+	}
+
+	notify(g.observers, e)
+}
+
+// notify calls the method of every observer for the event type
+func notify(observers []Observer, e GameEvent) {
+	for _, o := range observers {
+		switch e.Type {
+		case EventMatchStart:
+			o.OnMatchStart(e)
+		case EventGameStart:
+			o.OnGameStart(e)
+		case EventTurnStart:
+			o.OnTurnStart(e)
+		case EventRoll:
+			o.OnRoll(e)
+		case EventChoice:
+			o.OnChoice(e)
+		case EventSquelch:
+			o.OnSquelch(e)
+		case EventRollover:
+			o.OnRollover(e)
+		case EventReroll:
+			o.OnReroll(e)
+		case EventOvertime:
+			o.OnOvertime(e)
+		case EventGameEnd:
+			o.OnGameEnd(e)
+		case EventMatchEnd:
+			o.OnMatchEnd(e)
+		}
+	}
+}
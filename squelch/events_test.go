@@ -0,0 +1,123 @@
+package squelch
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGame_Events(t *testing.T) {
+	p1 := getMockPlayerTakeHighestXTimes(t, "1", 1)
+	p2 := getMockPlayerTakeHighestXTimes(t, "2", 1)
+	g := NewGame([]Player{p1, p2}, 2000, "m", "g", 0)
+	g.roll = getRollFunc(t, []string{"123456", "111111", "123446"})
+
+	var events []GameEvent
+	g.AddObserver(EventFunc(func(e GameEvent) {
+		events = append(events, e)
+	}))
+
+	if _, err := g.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	want := []EventType{
+		EventGameStart,
+		EventTurnStart, EventRoll, EventChoice,
+		EventTurnStart, EventRoll, EventChoice, EventOvertime,
+		EventTurnStart, EventRoll, EventChoice,
+		EventGameEnd,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Event count incorrect, want %v got %v", len(want), len(events))
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Errorf("Event %v incorrect, want %v got %v", i, want[i], e.Type)
+		}
+		if e.MatchID != "m" || e.GameID != "g" || len(e.Players) != 2 || len(e.Scores) != 2 {
+			t.Errorf("Event %v missing game info: %+v", i, e)
+		}
+	}
+
+	// the second player's 111111 puts them over the target
+	choice := events[6]
+	if choice.BotIndex != 1 || !choice.Stay || choice.Scores[1] < 2000 || choice.IsFinalRound {
+		t.Errorf("Winning choice incorrect, got %+v", choice)
+	}
+	if ot := events[7]; ot.BotIndex != 1 || !ot.IsFinalRound {
+		t.Errorf("Overtime incorrect, got %+v", ot)
+	}
+	if last := events[8]; last.BotIndex != 0 || !last.IsFinalRound {
+		t.Errorf("Final turn incorrect, got %+v", last)
+	}
+	if end := events[len(events)-1]; end.WinnerIndex != 1 || end.Err != "" {
+		t.Errorf("Game end incorrect, got %+v", end)
+	}
+}
+
+func TestGame_RolloverEvent(t *testing.T) {
+	// player 0 takes all 6 dice then stays on the rollover roll
+	p1 := getMockPlayerTakeHighestXTimes(t, "1", 2)
+	p2 := getMockPlayerTakeHighestXTimes(t, "2", 1)
+	g := NewGame([]Player{p1, p2}, 1000, "m", "g", 0)
+	g.roll = getRollFunc(t, []string{"111111", "123456", "111111"})
+
+	var types []EventType
+	g.AddObserver(EventFunc(func(e GameEvent) {
+		types = append(types, e.Type)
+	}))
+
+	if _, err := g.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if len(types) < 6 || types[4] != EventRollover {
+		t.Errorf("Rollover event missing, got %v", types)
+	}
+}
+
+func TestTournament_Observers(t *testing.T) {
+	stay := func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		return &PlayerChoice{options[0].ID, true}, nil
+	}
+	p1 := getMockPlayerAnyCallback("1", stay)
+	p2 := getMockPlayerAnyCallback("2", stay)
+	tr := NewTournament(2, 2, 500, []Player{p1, p2})
+
+	buf := &bytes.Buffer{}
+	rec := NewRecorder(buf)
+	tr.AddObserver(rec)
+
+	if _, err := tr.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatalf("Error recording: %v", err)
+	}
+
+	events, err := ReadEvents(buf)
+	if err != nil {
+		t.Fatalf("Error reading: %v", err)
+	}
+
+	if first := events[0]; first.Type != EventMatchStart || len(first.Players) != 2 {
+		t.Errorf("First event incorrect, got %+v", first)
+	}
+	last := events[len(events)-1]
+	if last.Type != EventMatchEnd || last.Wins[0]+last.Wins[1] != 2 {
+		t.Errorf("Last event incorrect, got %+v", last)
+	}
+
+	games := 0
+	for _, e := range events {
+		if e.MatchID != events[0].MatchID {
+			t.Errorf("Event from another match: %+v", e)
+		}
+		if e.Type == EventGameEnd {
+			games++
+		}
+	}
+	if games != 2 {
+		t.Errorf("Game count incorrect, want 2 got %v", games)
+	}
+}
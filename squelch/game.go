@@ -2,8 +2,9 @@ package squelch
 
 import (
 	"container/ring"
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"sort"
 	"strconv"
@@ -13,23 +14,36 @@ import (
 type Game struct {
 	players     *ring.Ring
 	playerCount int
+	// turnOrder is the bot indexes from the first player
+	turnOrder []int
+	// teams are the team of each bot index in a team game, nil otherwise
+	teams       []int
 	targetScore int
 	matchID     string
 	gameID      string
 	roll        func(diceCount int) string
 	lastTurnID  int
+	observers   []Observer
+	log         *slog.Logger
+	// ctx stops the game between turns once it's done
+	ctx context.Context
 }
 
 // GameResult is the return of the Run method
 type GameResult struct {
+	// WinnerIndex is a player on the winning team in a team game
 	WinnerIndex int
-	ErrIndex    int
+	// ErrIndex is the player that failed, -1 if the game was stopped
+	ErrIndex int
 }
 type gamePlayer struct {
 	Player
 	score      int
+	handicap   Handicap
+	rerolls    int
 	playedInOT bool
 	info       *PlayerInfo
+	infoErr    error
 	index      int
 	lastTurn   *PlayerTurn
 }
@@ -47,17 +61,19 @@ func NewGame(players []Player, targetScore int, matchID, gameID string, startPla
 		roll:        rollDice,
 		playerCount: len(players),
 		lastTurnID:  0,
+		log:         logger().With("match", matchID, "game", gameID),
 	}
 
 	r := ring.New(g.playerCount)
 	var start *ring.Ring
 	// setup our ring of players
 	for i, p := range players {
-		info, _ := p.Info()
+		info, err := p.Info()
 		r.Value = &gamePlayer{
-			Player: p,
-			info:   info,
-			index:  i,
+			Player:  p,
+			info:    info,
+			infoErr: err,
+			index:   i,
 		}
 		if i == startPlayerIndex {
 			start = r
@@ -66,43 +82,168 @@ func NewGame(players []Player, targetScore int, matchID, gameID string, startPla
 	}
 
 	g.players = start
+	for r := start; len(g.turnOrder) < g.playerCount; r = r.Next() {
+		g.turnOrder = append(g.turnOrder, r.Value.(*gamePlayer).index)
+	}
 
 	return g
 }
 
+// SetRollFunc replaces the dice roller, e.g. with scripted rolls. The func
+// must return diceCount sorted dice.
+func (g *Game) SetRollFunc(roll func(diceCount int) string) {
+	g.roll = roll
+}
+
+// SetTeams makes it a team game, teams has the team of each bot index,
+// numbered from 0. Teammates' scores count together toward the target, the
+// team with the highest total wins, and once a team reaches the target every
+// player on the other teams gets a final turn.
+func (g *Game) SetTeams(teams []int) {
+	g.teams = teams
+}
+
+// SetHandicaps gives each bot index its handicap, nil for none. It must be
+// called before Run. Target reductions are per player, so tournaments don't
+// allow them in team games.
+func (g *Game) SetHandicaps(handicaps []Handicap) {
+	r := g.players
+	for i := 0; i < g.playerCount; i++ {
+		p := r.Value.(*gamePlayer)
+		if p.index < len(handicaps) {
+			p.handicap = handicaps[p.index]
+		}
+		r = r.Next()
+	}
+}
+
+// target is the score the player needs to start the final round
+func (g *Game) target(p *gamePlayer) int {
+	return g.targetScore - p.handicap.TargetReduction
+}
+
+// team is the player's team, every player is their own team unless it's a
+// team game
+func (g *Game) team(p *gamePlayer) int {
+	if g.teams == nil {
+		return p.index
+	}
+	return g.teams[p.index]
+}
+
+// teamScore is the total score of the team
+func (g *Game) teamScore(team int) int {
+	total := 0
+	r := g.players
+	for i := 0; i < g.playerCount; i++ {
+		if p := r.Value.(*gamePlayer); g.team(p) == team {
+			total += p.score
+		}
+		r = r.Next()
+	}
+	return total
+}
+
+// winner is the player, or a player of the team, with the highest final
+// score. Ties go to current, who got there first.
+func (g *Game) winner(current *gamePlayer) *gamePlayer {
+	best := current
+	r := g.players
+	for i := 0; i < g.playerCount; i++ {
+		p := r.Value.(*gamePlayer)
+		if best == nil || g.teamScore(g.team(p)) > g.teamScore(g.team(best)) {
+			best = p
+		}
+		r = r.Next()
+	}
+	return best
+}
+
+// allPlayedInOT is set once every player has had their final turn
+func (g *Game) allPlayedInOT() bool {
+	r := g.players
+	for i := 0; i < g.playerCount; i++ {
+		if !r.Value.(*gamePlayer).playedInOT {
+			return false
+		}
+		r = r.Next()
+	}
+	return true
+}
+
+// SetContext stops the game before the next turn once ctx is done. Run then
+// returns ctx's error with an ErrIndex of -1.
+func (g *Game) SetContext(ctx context.Context) {
+	g.ctx = ctx
+}
+
 // Run executes a game of squelch and returns a GameResult.
 func (g *Game) Run() (GameResult, error) {
+	g.emit(GameEvent{Type: EventGameStart})
+
+	res, err := g.run()
+
+	end := GameEvent{Type: EventGameEnd, WinnerIndex: res.WinnerIndex}
+	if err != nil {
+		end.WinnerIndex, end.BotIndex, end.Err = -1, res.ErrIndex, err.Error()
+	}
+	g.emit(end)
+
+	return res, err
+}
+
+func (g *Game) run() (GameResult, error) {
+	// handicaps start the game
+	r := g.players
+	for i := 0; i < g.playerCount; i++ {
+		p := r.Value.(*gamePlayer)
+		if p.infoErr != nil {
+			return GameResult{ErrIndex: p.index}, fmt.Errorf("Error getting info for player %v: %v", p.index, p.infoErr)
+		}
+		p.score, p.rerolls = p.handicap.StartScore, p.handicap.Rerolls
+		r = r.Next()
+	}
+
 	//  notify all players the game is starting
 	g.notifyAllPlayers(func(p *gamePlayer) error {
 		return p.GameStart(g.matchID, g.gameID)
 	})
 
 	isOvertime := false
-	var currentWinner *gamePlayer
+	var currentWinner, overtimeBy *gamePlayer
 
 	// run through our players until we have a winner
 	for {
 		p := g.players.Value.(*gamePlayer)
 
-		debug("%v - Start Turn", p.name())
-
 		otherPlayerTurns := g.getOtherPlayerTurns()
 		// first, our game end conditions:
 		// 	we're in the "overtime" round (after someone gets the threshold score w/o squelching)
-		//  the player has already gone in the OT round
+		//  every player has already gone in the OT round
 		if isOvertime {
-			debug("%v - OT round", p.name())
+			g.log.Debug("final round", "player", p.name(), "played", p.playedInOT)
 			if p.playedInOT {
+				if !g.allPlayedInOT() {
+					// a teammate of whoever reached the target, skip them
+					g.players = g.players.Next()
+					continue
+				}
+				// a head start counts even if the player never banked
+				winner := g.winner(currentWinner)
 				//notify all players game ended with the result
 				g.notifyAllPlayers(func(p *gamePlayer) error {
-					return p.GameEnd(g.matchID, g.gameID, otherPlayerTurns, currentWinner.index)
+					return p.GameEnd(g.matchID, g.gameID, otherPlayerTurns, winner.index)
 				})
-				return GameResult{WinnerIndex: currentWinner.index}, nil
+				return GameResult{WinnerIndex: winner.index}, nil
 			}
 			// tag that we've had our shot in OT
 			p.playedInOT = true
 		}
 
+		if g.ctx != nil && g.ctx.Err() != nil {
+			return GameResult{ErrIndex: -1}, fmt.Errorf("game stopped: %w", g.ctx.Err())
+		}
+
 		//TURN START
 		p.lastTurn = &PlayerTurn{
 			BotIndex:    p.index,
@@ -110,18 +251,23 @@ func (g *Game) Run() (GameResult, error) {
 			EndPoints:   p.score,
 		}
 		turnID := g.nextTurnID()
-		if err := p.TurnStart(g.matchID, g.gameID, turnID, p.score, otherPlayerTurns, isOvertime); err != nil {
+		tlog := g.log.With("turn", turnID, "player", p.name())
+		tlog.Debug("turn start", "score", p.score)
+		g.emit(GameEvent{Type: EventTurnStart, TurnID: turnID, BotIndex: p.index, IsFinalRound: isOvertime})
+		if err := p.TurnStart(g.matchID, g.gameID, turnID, p.score, otherPlayerTurns, isOvertime, g.state(p, 0, overtimeBy)); err != nil {
 			return GameResult{ErrIndex: p.index}, err
 		}
 		diceCount := 6
 		points := 0
+		startedOT := false
 		turnOptionCount := 0
 		for {
 			// 1. if there are 0 dice in the pool then reset to 6 dice
 			if diceCount == 0 {
-				debug("%v - Rollover", p.name())
+				tlog.Debug("rollover", "turnPoints", points)
 				// called a "rollover"
 				diceCount = 6
+				g.emit(GameEvent{Type: EventRollover, TurnID: turnID, BotIndex: p.index, IsFinalRound: isOvertime, TurnPoints: points})
 			}
 
 			// roll the dice for the player
@@ -129,10 +275,20 @@ func (g *Game) Run() (GameResult, error) {
 			options := getDiceOptions(turnOptionCount, rawRoll)
 			turnOptionCount += len(options)
 
+			// a handicap reroll saves a squelch, roll the same dice again
+			if len(options) == 0 && p.rerolls > 0 {
+				p.rerolls--
+				tlog.Debug("reroll", "dice", rawRoll, "left", p.rerolls)
+				p.lastTurn.Rolls = append(p.lastTurn.Rolls, PlayerRoll{rawRoll, "", 0})
+				g.emit(GameEvent{Type: EventReroll, TurnID: turnID, BotIndex: p.index, IsFinalRound: isOvertime, TurnPoints: points, Dice: rawRoll})
+				continue
+			}
+
 			// if there are 0 options, it's a squelch, no points, turn over
 			if len(options) == 0 {
-				debug("%v - Squelch", p.name())
+				tlog.Debug("squelch", "dice", rawRoll, "lost", points)
 				p.lastTurn.Rolls = append(p.lastTurn.Rolls, PlayerRoll{rawRoll, "", 0})
+				g.emit(GameEvent{Type: EventSquelch, TurnID: turnID, BotIndex: p.index, IsFinalRound: isOvertime, TurnPoints: points, Dice: rawRoll})
 				err := p.Squelch(g.matchID, g.gameID, turnID, rawRoll)
 				if err != nil {
 					return GameResult{ErrIndex: p.index}, err
@@ -140,9 +296,11 @@ func (g *Game) Run() (GameResult, error) {
 				break
 			}
 
+			g.emit(GameEvent{Type: EventRoll, TurnID: turnID, BotIndex: p.index, IsFinalRound: isOvertime, TurnPoints: points, Dice: rawRoll, Options: options})
+
 			// let the player choose which point option to take
 			// and if to keep rolling the remaining dice or hold
-			choice, err := p.Choose(g.matchID, g.gameID, turnID, rawRoll, options)
+			choice, err := p.Choose(g.matchID, g.gameID, turnID, rawRoll, options, g.state(p, points, overtimeBy))
 			if err != nil {
 				return GameResult{ErrIndex: p.index}, err
 			}
@@ -158,23 +316,37 @@ func (g *Game) Run() (GameResult, error) {
 
 			p.lastTurn.Rolls = append(p.lastTurn.Rolls, PlayerRoll{rawRoll, opt.DieValues, opt.Points})
 
-			debug("%v - %v points so far", p.name(), points)
+			tlog.Debug("took option", "dice", rawRoll, "take", opt.DieValues, "turnPoints", points)
 			// if the player chooses to hold, add running points to player score, turn over.
 			if choice.Stay {
 				p.score += points
 				p.lastTurn.EndPoints = p.score
-				debug("%v - hold, %v total points", p.name(), p.score)
+				tlog.Debug("stay", "score", p.score)
 				// figure out current winner
-				if currentWinner == nil || p.score > currentWinner.score {
+				total := g.teamScore(g.team(p))
+				if currentWinner == nil || total > g.teamScore(g.team(currentWinner)) {
 					currentWinner = p
 				}
 
 				// if we're not in OT and someone went over, then we're in OT
-				// and the player that goes over is done
-				if !isOvertime && p.score >= g.targetScore {
+				// and the player that goes over, and their team, is done
+				if !isOvertime && total >= g.target(p) {
 					isOvertime = true
+					overtimeBy = p
+					for r := g.players.Next(); r != g.players; r = r.Next() {
+						if tp := r.Value.(*gamePlayer); g.team(tp) == g.team(p) {
+							tp.playedInOT = true
+						}
+					}
 					p.playedInOT = true
+					startedOT = true
 				}
+			}
+			g.emit(GameEvent{Type: EventChoice, TurnID: turnID, BotIndex: p.index, IsFinalRound: isOvertime && !startedOT, TurnPoints: points, Take: opt.DieValues, Points: opt.Points, Stay: choice.Stay})
+			if startedOT {
+				g.emit(GameEvent{Type: EventOvertime, TurnID: turnID, BotIndex: p.index, IsFinalRound: true})
+			}
+			if choice.Stay {
 				break
 			}
 
@@ -192,6 +364,46 @@ func (g *Game) nextTurnID() string {
 	return strconv.Itoa(g.lastTurnID)
 }
 
+// state is the game as it stands for p's turn, overtimeBy is nil before the
+// final round
+func (g *Game) state(p *gamePlayer, turnPoints int, overtimeBy *gamePlayer) GameState {
+	s := GameState{
+		BotIndex:         p.index,
+		Scores:           make([]int, g.playerCount),
+		Targets:          make([]int, g.playerCount),
+		TurnOrder:        append([]int{}, g.turnOrder...),
+		TargetScore:      g.targetScore,
+		TurnNumber:       g.lastTurnID,
+		TurnPoints:       turnPoints,
+		RerollsLeft:      p.rerolls,
+		Rolls:            append([]PlayerRoll{}, p.lastTurn.Rolls...),
+		IsFinalRound:     overtimeBy != nil,
+		OvertimeBotIndex: -1,
+	}
+	if overtimeBy != nil {
+		s.OvertimeBotIndex = overtimeBy.index
+	}
+
+	r := g.players
+	for i := 0; i < g.playerCount; i++ {
+		gp := r.Value.(*gamePlayer)
+		s.Scores[gp.index] = gp.score
+		s.Targets[gp.index] = g.target(gp)
+		r = r.Next()
+	}
+
+	if g.teams != nil {
+		s.Teams = append([]int{}, g.teams...)
+		for i, t := range g.teams {
+			for len(s.TeamScores) <= t {
+				s.TeamScores = append(s.TeamScores, 0)
+			}
+			s.TeamScores[t] += s.Scores[i]
+		}
+	}
+	return s
+}
+
 func getOption(options []ScoringOption, id string) *ScoringOption {
 	for _, o := range options {
 		if o.ID == id {
@@ -203,7 +415,7 @@ func getOption(options []ScoringOption, id string) *ScoringOption {
 }
 
 func (g *Game) getOtherPlayerTurns() []PlayerTurn {
-	turns := make([]PlayerTurn, g.playerCount-1)
+	turns := make([]PlayerTurn, 0, g.playerCount-1)
 	// skip us, just iterate and gather the other players
 	for p := g.players.Next(); p != g.players; p = p.Next() {
 		if p.Value.(*gamePlayer).lastTurn != nil {
@@ -234,16 +446,24 @@ func (g *Game) notifyAllPlayers(f func(p *gamePlayer) error) error {
 	return err
 }
 
-func debug(format string, args ...interface{}) {
-	log.Printf(format, args...)
+func rollDice(diceCount int) string {
+	return rollDiceWith(rand.Intn, diceCount)
+}
+
+// RandRoller returns a dice roller for SetRollFunc that uses r, so a
+// game can be replayed from a seed
+func RandRoller(r *rand.Rand) func(diceCount int) string {
+	return func(diceCount int) string {
+		return rollDiceWith(r.Intn, diceCount)
+	}
 }
 
-func rollDice(diceCount int) string {
+func rollDiceWith(intn func(int) int, diceCount int) string {
 	// give a number of d6, randomly generate a string and get our list
 	// of options from the pre-generated table
 	b := make([]rune, diceCount)
 	for i := range b {
-		b[i] = rune('1' + rand.Intn(6))
+		b[i] = rune('1' + intn(6))
 	}
 
 	//sort our dice
@@ -256,7 +476,7 @@ func rollDice(diceCount int) string {
 
 func getDiceOptions(turnOptionCount int, sortedDice string) []ScoringOption {
 	//clone it and set IDs
-	opts := append([]ScoringOption{}, allOptions[sortedDice]...)
+	opts := append([]ScoringOption{}, scoringOptions()[sortedDice]...)
 	for i := range opts {
 		opts[i].ID = strconv.Itoa(i + turnOptionCount)
 	}
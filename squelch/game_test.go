@@ -1,8 +1,10 @@
 package squelch
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -47,6 +49,30 @@ func TestGame_MultipleRolls(t *testing.T) {
 
 }
 
+// noInfoPlayer is a bot that can't be asked who it is
+type noInfoPlayer struct {
+	*MockPlayer
+}
+
+func (p noInfoPlayer) Info() (*PlayerInfo, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestGame_InfoError(t *testing.T) {
+	p1 := getMockPlayerTakeHighestXTimes(t, "1", 1)
+	p2 := noInfoPlayer{getMockPlayerTakeHighestXTimes(t, "2", 1)}
+	g := NewGame([]Player{p1, p2}, 2000, "m", "g", 0)
+	g.roll = getRollFunc(t, []string{"123456"})
+
+	res, err := g.Run()
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+	if want, got := 1, res.ErrIndex; want != got {
+		t.Errorf("ErrIndex incorrect, want %v got %v", want, got)
+	}
+}
+
 func getMockPlayerTakeHighestXTimes(t *testing.T, name string, x int) *MockPlayer {
 	turns := make(map[string]map[string]struct{})
 	choiceNum := 0
@@ -86,8 +112,8 @@ func getMockPlayerTakeHighestXTimes(t *testing.T, name string, x int) *MockPlaye
 	//p.On("MatchEnd")
 	p.On("GameStart", "m", "g").Return(nil)
 	p.On("GameEnd", "m", "g", mock.Anything, mock.Anything).Return(nil)
-	p.On("TurnStart", "m", "g", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
-	p.On("Choose", "m", "g", mock.Anything, mock.Anything, mock.Anything)
+	p.On("TurnStart", "m", "g", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	p.On("Choose", "m", "g", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	p.On("Squelch", "m", "g", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
 		choiceNum = 0
 	})
@@ -112,3 +138,158 @@ func getRollFunc(t *testing.T, rolls []string) func(int) string {
 		return val
 	}
 }
+
+func TestGame_State(t *testing.T) {
+	p := []*MockPlayer{
+		getMockPlayerTakeHighestXTimes(t, "0", 1),
+		getMockPlayerTakeHighestXTimes(t, "1", 1),
+		getMockPlayerTakeHighestXTimes(t, "2", 1),
+	}
+	// player 1 starts, player 2 reaches the target
+	g := NewGame([]Player{p[0], p[1], p[2]}, 2000, "m", "g", 1)
+	g.roll = getRollFunc(t, []string{"123456", "111111", "123446", "123446"})
+
+	if _, err := g.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var turnStart, choose mock.Call
+	for _, c := range p[0].Calls {
+		switch c.Method {
+		case "TurnStart":
+			turnStart = c
+		case "Choose":
+			choose = c
+		}
+	}
+
+	state := turnStart.Arguments.Get(6).(GameState)
+	want := GameState{
+		BotIndex:         0,
+		Scores:           []int{0, 1500, 2000},
+		TurnOrder:        []int{1, 2, 0},
+		TargetScore:      2000,
+		Targets:          []int{2000, 2000, 2000},
+		TurnNumber:       3,
+		Rolls:            []PlayerRoll{},
+		IsFinalRound:     true,
+		OvertimeBotIndex: 2,
+	}
+	assert.Equal(t, want, state)
+	assert.Equal(t, 2000, state.HighScore())
+
+	// the turns of players that haven't gone aren't padded in
+	others := turnStart.Arguments.Get(4).([]PlayerTurn)
+	if len(others) != 2 || others[0].BotIndex != 1 || others[1].BotIndex != 2 {
+		t.Errorf("Other player turns incorrect: %+v", others)
+	}
+
+	if state := choose.Arguments.Get(5).(GameState); state.TurnNumber != 3 || state.TurnPoints != 0 || !state.IsFinalRound {
+		t.Errorf("Choose state incorrect: %+v", state)
+	}
+}
+
+func TestGame_Teams(t *testing.T) {
+	p := []*MockPlayer{
+		getMockPlayerTakeHighestXTimes(t, "a1", 1),
+		getMockPlayerTakeHighestXTimes(t, "b1", 1),
+		getMockPlayerTakeHighestXTimes(t, "a2", 1),
+		getMockPlayerTakeHighestXTimes(t, "b2", 1),
+	}
+	g := NewGame([]Player{p[0], p[1], p[2], p[3]}, 2000, "m", "g", 0)
+	g.SetTeams([]int{0, 1, 0, 1})
+	// a2 takes team a past the target, then only team b plays the final
+	// round, running out of rolls would fail the test
+	g.roll = getRollFunc(t, []string{"123456", "122346", "111234", "223466", "223466"})
+
+	res, err := g.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	// any player on the team
+	if res.WinnerIndex != 0 && res.WinnerIndex != 2 {
+		t.Errorf("Winner incorrect, want team a got %v", res.WinnerIndex)
+	}
+
+	var state GameState
+	for _, c := range p[3].Calls {
+		if c.Method == "TurnStart" {
+			state = c.Arguments.Get(6).(GameState)
+		}
+	}
+	assert.Equal(t, []int{0, 1, 0, 1}, state.Teams)
+	assert.Equal(t, []int{2500, 100}, state.TeamScores)
+	assert.Equal(t, 2, state.OvertimeBotIndex)
+	assert.Equal(t, 100, state.TeamScore())
+	assert.Equal(t, 2500, state.HighScore())
+}
+
+func TestGame_Handicaps(t *testing.T) {
+	// a head start and a reroll that saves the first squelch
+	p1 := getMockPlayerTakeHighestXTimes(t, "1", 1)
+	p2 := getMockPlayerTakeHighestXTimes(t, "2", 1)
+	g := NewGame([]Player{p1, p2}, 2000, "m", "g", 0)
+	g.SetHandicaps([]Handicap{{StartScore: 1000, Rerolls: 1}, {}})
+	g.roll = getRollFunc(t, []string{"223466", "123456", "122346"})
+	var events []GameEvent
+	g.AddObserver(EventFunc(func(e GameEvent) { events = append(events, e) }))
+
+	res, err := g.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := 0, res.WinnerIndex; want != got {
+		t.Errorf("Winner incorrect, want %v got %v", want, got)
+	}
+
+	var state GameState
+	for _, c := range p1.Calls {
+		if c.Method == "Choose" {
+			state = c.Arguments.Get(5).(GameState)
+		}
+	}
+	if state.Score() != 1000 || state.RerollsLeft != 0 || len(state.Rolls) != 1 || state.Rolls[0].DieValues != "223466" {
+		t.Errorf("State incorrect: %+v", state)
+	}
+	// the reroll is played out for observers between the turn start and the roll
+	if len(events) < 4 || events[2].Type != EventReroll || events[2].Dice != "223466" || events[3].Type != EventRoll {
+		t.Errorf("Events incorrect: %+v", events)
+	}
+
+	// a lower target starts the final round sooner
+	p1 = getMockPlayerTakeHighestXTimes(t, "1", 1)
+	p2 = getMockPlayerTakeHighestXTimes(t, "2", 1)
+	g = NewGame([]Player{p1, p2}, 2000, "m", "g", 0)
+	g.SetHandicaps([]Handicap{{}, {TargetReduction: 500}})
+	g.roll = getRollFunc(t, []string{"122346", "123456", "223466"})
+
+	if res, err = g.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := 1, res.WinnerIndex; want != got {
+		t.Errorf("Winner incorrect, want %v got %v", want, got)
+	}
+	// the bot is told its lowered target
+	for _, c := range p2.Calls {
+		if c.Method == "Choose" {
+			state = c.Arguments.Get(5).(GameState)
+		}
+	}
+	assert.Equal(t, []int{2000, 1500}, state.Targets)
+	assert.Equal(t, 1500, state.Target())
+	assert.Equal(t, 2000, state.TargetScore)
+
+	// a head start counts even if the player never banks
+	p1 = getMockPlayerTakeHighestXTimes(t, "1", 1)
+	p2 = getMockPlayerTakeHighestXTimes(t, "2", 1)
+	g = NewGame([]Player{p1, p2}, 2000, "m", "g", 0)
+	g.SetHandicaps([]Handicap{{StartScore: 1900}, {TargetReduction: 500}})
+	g.roll = getRollFunc(t, []string{"223466", "123456", "223466"})
+
+	if res, err = g.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := 0, res.WinnerIndex; want != got {
+		t.Errorf("Winner incorrect, want %v got %v", want, got)
+	}
+}
@@ -0,0 +1,240 @@
+package squelch
+
+import (
+	"errors"
+	"math/bits"
+	"net"
+	"sync"
+	"time"
+)
+
+// LatencyStats summarizes the response times of one callback type for a bot.
+// Durations are in nanoseconds when exported as JSON.
+type LatencyStats struct {
+	Calls int           `json:"calls"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// BotLatency is the response time summary of a single entrant, keyed by
+// callback name (e.g. "Choose")
+type BotLatency struct {
+	EntrantIndex int                     `json:"entrantIndex"`
+	Callbacks    map[string]LatencyStats `json:"callbacks"`
+	Timeouts     int                     `json:"timeouts"`
+}
+
+// CallbackObserver is an Observer that also wants to know how long every
+// callback to a bot takes. Observers added to a Tournament that implement it
+// are told about every call, named like "Choose".
+type CallbackObserver interface {
+	OnCallback(botName, callback string, d time.Duration, err error)
+}
+
+// latencyRecorder counts every callback duration made to the entrants in a
+// histogram per callback, so memory stays the same however long it runs
+type latencyRecorder struct {
+	sync     sync.Mutex
+	samples  []map[string]*histogram
+	timeouts []int
+
+	// names and hooks are set to pass every call on to callback observers
+	names []string
+	hooks []CallbackObserver
+}
+
+func newLatencyRecorder(entrantCount int) *latencyRecorder {
+	l := &latencyRecorder{
+		samples:  make([]map[string]*histogram, entrantCount),
+		timeouts: make([]int, entrantCount),
+	}
+	for i := range l.samples {
+		l.samples[i] = make(map[string]*histogram)
+	}
+	return l
+}
+
+func (l *latencyRecorder) record(entrantIdx int, callback string, start time.Time, err error) {
+	d := time.Since(start)
+
+	l.sync.Lock()
+	h, ok := l.samples[entrantIdx][callback]
+	if !ok {
+		h = &histogram{}
+		l.samples[entrantIdx][callback] = h
+	}
+	h.add(d)
+	if isTimeout(err) {
+		l.timeouts[entrantIdx]++
+	}
+	l.sync.Unlock()
+
+	for _, h := range l.hooks {
+		h.OnCallback(l.names[entrantIdx], callback, d, err)
+	}
+}
+
+// stats returns the summary for every entrant in entrant index order
+func (l *latencyRecorder) stats() []BotLatency {
+	l.sync.Lock()
+	defer l.sync.Unlock()
+
+	res := make([]BotLatency, len(l.samples))
+	for i, cbs := range l.samples {
+		res[i] = BotLatency{
+			EntrantIndex: i,
+			Callbacks:    make(map[string]LatencyStats, len(cbs)),
+			Timeouts:     l.timeouts[i],
+		}
+		for cb, h := range cbs {
+			res[i].Callbacks[cb] = h.summarize()
+		}
+	}
+
+	return res
+}
+
+// histogramSteps is how many buckets each power of two is split into, so
+// percentiles are within 1/histogramSteps of the real durations
+const histogramSteps = 16
+
+// histogram counts durations in buckets that widen with the duration. The
+// first histogramSteps nanoseconds get a bucket each, after that every power
+// of two is split into histogramSteps equal buckets.
+type histogram struct {
+	counts [(64 - 3) * histogramSteps]int
+	calls  int
+	max    time.Duration
+}
+
+func (h *histogram) add(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[bucket(uint64(d))]++
+	h.calls++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+func (h *histogram) summarize() LatencyStats {
+	return LatencyStats{
+		Calls: h.calls,
+		P50:   h.percentile(50),
+		P95:   h.percentile(95),
+		P99:   h.percentile(99),
+		Max:   h.max,
+	}
+}
+
+// percentile uses the nearest-rank method, returning the top of the bucket
+// the rank falls in but never more than the longest duration seen
+func (h *histogram) percentile(p int) time.Duration {
+	rank := (p*h.calls + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	seen := 0
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			if top := bucketTop(i); top < uint64(h.max) {
+				return time.Duration(top)
+			}
+			break
+		}
+	}
+	return h.max
+}
+
+// bucket is the histogram bucket of v nanoseconds
+func bucket(v uint64) int {
+	if v < histogramSteps {
+		return int(v)
+	}
+	// keep the top 5 bits, the leading 1 and 4 bits of steps
+	shift := bits.Len64(v) - 5
+	return (shift+1)*histogramSteps + int(v>>shift) - histogramSteps
+}
+
+// bucketTop is the longest duration in nanoseconds that falls in bucket i
+func bucketTop(i int) uint64 {
+	if i < histogramSteps {
+		return uint64(i)
+	}
+	shift := i/histogramSteps - 1
+	step := uint64(i%histogramSteps + histogramSteps)
+	return (step+1)<<shift - 1
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return err != nil && errors.As(err, &ne) && ne.Timeout()
+}
+
+// timedPlayer records how long every call to the wrapped player takes
+type timedPlayer struct {
+	Player
+	entrantIdx int
+	rec        *latencyRecorder
+}
+
+func (p *timedPlayer) Info() (*PlayerInfo, error) {
+	start := time.Now()
+	info, err := p.Player.Info()
+	p.rec.record(p.entrantIdx, "Info", start, err)
+	return info, err
+}
+
+func (p *timedPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []Handicap) error {
+	start := time.Now()
+	err := p.Player.MatchStart(matchID, dieCount, maxPoints, gameCount, yourBotIndex, botNames, handicaps)
+	p.rec.record(p.entrantIdx, "MatchStart", start, err)
+	return err
+}
+
+func (p *timedPlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	start := time.Now()
+	err := p.Player.MatchEnd(matchID, winsByBotIndex)
+	p.rec.record(p.entrantIdx, "MatchEnd", start, err)
+	return err
+}
+
+func (p *timedPlayer) GameStart(matchID, gameID string) error {
+	start := time.Now()
+	err := p.Player.GameStart(matchID, gameID)
+	p.rec.record(p.entrantIdx, "GameStart", start, err)
+	return err
+}
+
+func (p *timedPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []PlayerTurn, winnerBotIndex int) error {
+	start := time.Now()
+	err := p.Player.GameEnd(matchID, gameID, finalPlayerTurns, winnerBotIndex)
+	p.rec.record(p.entrantIdx, "GameEnd", start, err)
+	return err
+}
+
+func (p *timedPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []PlayerTurn, isFinalRound bool, state GameState) error {
+	start := time.Now()
+	err := p.Player.TurnStart(matchID, gameID, turnID, startPoints, otherPlayerTurns, isFinalRound, state)
+	p.rec.record(p.entrantIdx, "TurnStart", start, err)
+	return err
+}
+
+func (p *timedPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []ScoringOption, state GameState) (*PlayerChoice, error) {
+	start := time.Now()
+	c, err := p.Player.Choose(matchID, gameID, turnID, dieValues, options, state)
+	p.rec.record(p.entrantIdx, "Choose", start, err)
+	return c, err
+}
+
+func (p *timedPlayer) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	start := time.Now()
+	err := p.Player.Squelch(matchID, gameID, turnID, dieValues)
+	p.rec.record(p.entrantIdx, "Squelch", start, err)
+	return err
+}
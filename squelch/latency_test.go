@@ -0,0 +1,71 @@
+package squelch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	h := &histogram{}
+	for i := 100; i >= 1; i-- {
+		h.add(time.Duration(i) * time.Millisecond)
+	}
+
+	// the percentiles are as close as the buckets allow
+	s := h.summarize()
+	if s.Calls != 100 || s.Max != 100*time.Millisecond {
+		t.Errorf("Stats incorrect, got %+v", s)
+	}
+	for _, c := range []struct {
+		want, got time.Duration
+	}{{50 * time.Millisecond, s.P50}, {95 * time.Millisecond, s.P95}, {99 * time.Millisecond, s.P99}} {
+		if c.got < c.want || c.got > c.want+c.want/histogramSteps {
+			t.Errorf("Percentile incorrect, want %v got %v", c.want, c.got)
+		}
+	}
+
+	h = &histogram{}
+	h.add(time.Second)
+	if want, got := time.Second, h.summarize().P99; want != got {
+		t.Errorf("Single sample p99 incorrect, want %v got %v", want, got)
+	}
+}
+
+func TestHistogram_Buckets(t *testing.T) {
+	// every duration falls in a bucket that holds it and buckets are in order
+	last := -1
+	for _, v := range []uint64{0, 1, 15, 16, 17, 31, 32, 1000, 1 << 40, 1<<63 - 1, 1<<64 - 1} {
+		b := bucket(v)
+		if b < last || b >= len(histogram{}.counts) {
+			t.Fatalf("Bucket for %v incorrect, got %v", v, b)
+		}
+		if bucketTop(b) < v || (b > 0 && bucketTop(b-1) >= v) {
+			t.Errorf("Bucket %v doesn't hold %v", b, v)
+		}
+		last = b
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer s.Close()
+
+	u, _ := url.Parse(s.URL)
+	p := NewApiPlayer(*u)
+	p.client.Timeout = time.Millisecond
+
+	_, err := p.Info()
+	if !isTimeout(err) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if isTimeout(fmt.Errorf("wrapped: %w", errors.New("other"))) {
+		t.Errorf("expected a plain error not to be a timeout")
+	}
+}
@@ -0,0 +1,41 @@
+package squelch
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// currentLogger is where the engine logs to. It's silent unless the program
+// embedding squelch sets one.
+var currentLogger atomic.Pointer[slog.Logger]
+
+func init() {
+	currentLogger.Store(slog.New(discardHandler{}))
+}
+
+// logger is where the engine logs to right now
+func logger() *slog.Logger {
+	return currentLogger.Load()
+}
+
+// SetLogger sets the logger for matches and games. It's safe to call while
+// matches are running, games already started keep logging where they were;
+// nil silences logging again.
+//
+// Matches log at info, failing bots at warn and every turn, roll and choice
+// at debug, with match, game and turn fields.
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(discardHandler{})
+	}
+	currentLogger.Store(l)
+}
+
+// discardHandler drops everything without formatting it
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
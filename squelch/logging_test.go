@@ -0,0 +1,68 @@
+package squelch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	// the scoring options log when they're first made, get that done first
+	scoringOptions()
+
+	buf := &bytes.Buffer{}
+	SetLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(nil)
+
+	p1 := getMockPlayerTakeHighestXTimes(t, "1", 1)
+	p2 := getMockPlayerTakeHighestXTimes(t, "2", 1)
+	g := NewGame([]Player{p1, p2}, 2000, "m", "g", 0)
+	g.roll = getRollFunc(t, []string{"123456", "111111", "123446"})
+	if _, err := g.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if first["msg"] != "turn start" || first["match"] != "m" || first["game"] != "g" || first["turn"] != "1" {
+		t.Errorf("Turn start log incorrect, got %v", lines[0])
+	}
+
+	// silent again
+	SetLogger(nil)
+	buf.Reset()
+	p1 = getMockPlayerTakeHighestXTimes(t, "1", 1)
+	p2 = getMockPlayerTakeHighestXTimes(t, "2", 1)
+	g = NewGame([]Player{p1, p2}, 2000, "m", "g", 0)
+	g.roll = getRollFunc(t, []string{"123456", "111111", "123446"})
+	g.Run()
+	if buf.Len() > 0 {
+		t.Errorf("expected no logs, got %v", buf.String())
+	}
+}
+
+func TestSetLogger_WhileRunning(t *testing.T) {
+	defer SetLogger(nil)
+
+	tr := NewTournament(20, 2, 500, []Player{stayPlayer("a"), stayPlayer("b"), stayPlayer("c")})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			SetLogger(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug})))
+			SetLogger(nil)
+		}
+	}()
+
+	// run with -race to catch the logger changing under the matches
+	if _, err := tr.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	<-done
+}
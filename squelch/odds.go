@@ -0,0 +1,234 @@
+package squelch
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Roll is a distinct sorted roll of some number of dice and how likely it is
+type Roll struct {
+	Dice        string
+	Probability float64
+}
+
+var (
+	rollsSync sync.Once
+	allRolls  [7][]Roll
+)
+
+// Options returns the scoring options for a sorted roll, highest points first.
+// The options have no IDs; an empty list means the roll squelches.
+func Options(sortedDice string) []ScoringOption {
+	return append([]ScoringOption{}, scoringOptions()[sortedDice]...)
+}
+
+// Rolls returns every distinct sorted roll of diceCount (1-6) dice along
+// with its probability, nil for any other count. The returned slice must not
+// be modified.
+func Rolls(diceCount int) []Roll {
+	if diceCount < 1 || diceCount > 6 {
+		return nil
+	}
+	rollsSync.Do(func() {
+		for n := 1; n <= 6; n++ {
+			allRolls[n] = enumerateRolls(n)
+		}
+	})
+
+	return allRolls[diceCount]
+}
+
+// enumerateRolls counts every ordered roll of n dice into its sorted form
+func enumerateRolls(n int) []Roll {
+	total := 1
+	for i := 0; i < n; i++ {
+		total *= 6
+	}
+
+	counts := make(map[string]int)
+	b := make([]byte, n)
+	for i := 0; i < total; i++ {
+		v := i
+		for j := range b {
+			b[j] = byte('1' + v%6)
+			v /= 6
+		}
+		sorted := append([]byte(nil), b...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i] < sorted[j]
+		})
+		counts[string(sorted)]++
+	}
+
+	rolls := make([]Roll, 0, len(counts))
+	for d, c := range counts {
+		rolls = append(rolls, Roll{Dice: d, Probability: float64(c) / float64(total)})
+	}
+	sort.Slice(rolls, func(i, j int) bool {
+		return rolls[i].Dice < rolls[j].Dice
+	})
+
+	return rolls
+}
+
+// evLimit is the turn total past which rolling again is never worth it in
+// expected points, so the EV table stops there.
+const evLimit = 10000
+
+var (
+	evSync  sync.Once
+	evTable [][7]float64
+)
+
+// Position is the expected-value analysis of a point mid-turn
+type Position struct {
+	DiceCount  int
+	TurnPoints int
+	// SquelchProbability is the chance rolling DiceCount dice scores nothing
+	SquelchProbability float64
+	// RollEV is the expected points banked this turn by rolling now and then
+	// playing to maximize expected points
+	RollEV float64
+	// BestEV is the better of staying on TurnPoints and RollEV
+	BestEV float64
+}
+
+// ShouldRoll is true if rolling again beats staying in expected points
+func (p Position) ShouldRoll() bool {
+	return p.RollEV > float64(p.TurnPoints)
+}
+
+// OptionEV is the expected value of taking a single scoring option
+type OptionEV struct {
+	ScoringOption
+	// DiceLeft is how many dice the next roll would use, 6 after a rollover
+	DiceLeft int
+	// EV is the expected points banked this turn after taking the option
+	// and then playing to maximize expected points
+	EV float64
+	// Stay is true if staying after taking the option is best
+	Stay bool
+}
+
+// SquelchProbability is the chance a roll of diceCount dice scores nothing,
+// 0 if it isn't 1 to 6 dice
+func SquelchProbability(diceCount int) float64 {
+	p := 0.0
+	for _, r := range Rolls(diceCount) {
+		if len(scoringOptions()[r.Dice]) == 0 {
+			p += r.Probability
+		}
+	}
+	return p
+}
+
+// Analyze returns the squelch chance and expected points for rolling
+// diceCount (1-6) dice with turnPoints (0 or more) already at risk.
+func Analyze(diceCount, turnPoints int) (Position, error) {
+	if err := checkPosition(diceCount, turnPoints); err != nil {
+		return Position{}, err
+	}
+
+	roll := rollEV(diceCount, turnPoints)
+	best := math.Max(roll, float64(turnPoints))
+
+	return Position{
+		DiceCount:          diceCount,
+		TurnPoints:         turnPoints,
+		SquelchProbability: SquelchProbability(diceCount),
+		RollEV:             roll,
+		BestEV:             best,
+	}, nil
+}
+
+// AnalyzeRoll returns the expected value of every scoring option in a sorted
+// roll of 1 to 6 dice, given the turn points taken before the roll. Options
+// are in the same order as Options returns them.
+func AnalyzeRoll(sortedDice string, turnPoints int) ([]OptionEV, error) {
+	if err := checkPosition(len(sortedDice), turnPoints); err != nil {
+		return nil, err
+	}
+
+	opts := scoringOptions()[sortedDice]
+	res := make([]OptionEV, len(opts))
+
+	for i, o := range opts {
+		left := len(sortedDice) - len(o.DieValues)
+		if left == 0 {
+			left = 6
+		}
+		points := turnPoints + o.Points
+		roll := rollEV(left, points)
+
+		res[i] = OptionEV{
+			ScoringOption: o,
+			DiceLeft:      left,
+			EV:            math.Max(roll, float64(points)),
+			Stay:          roll <= float64(points),
+		}
+	}
+
+	return res, nil
+}
+
+// checkPosition makes sure the dice and turn points can be analyzed
+func checkPosition(diceCount, turnPoints int) error {
+	if diceCount < 1 || diceCount > 6 {
+		return fmt.Errorf("a roll is 1 to 6 dice, got %v", diceCount)
+	}
+	if turnPoints < 0 {
+		return fmt.Errorf("turn points cannot be negative, got %v", turnPoints)
+	}
+	if turnPoints%50 != 0 {
+		return fmt.Errorf("turn points are a multiple of 50, got %v", turnPoints)
+	}
+	return nil
+}
+
+// rollEV is the expected points banked by rolling diceCount dice now
+func rollEV(diceCount, turnPoints int) float64 {
+	evSync.Do(buildEVTable)
+
+	// all points are multiples of 50
+	tp := turnPoints / 50
+	if tp >= len(evTable) {
+		// far enough out that rolling again is a losing bet, assume a stay
+		return float64(turnPoints) * (1 - SquelchProbability(diceCount))
+	}
+
+	return evTable[tp][diceCount]
+}
+
+// buildEVTable fills in the expected points of rolling for every turn total
+// up to evLimit, working down from the top since points only go up
+func buildEVTable() {
+	evTable = make([][7]float64, evLimit/50+1)
+
+	bestAfter := func(tp, n int) float64 {
+		stay := float64(tp * 50)
+		if tp >= len(evTable) {
+			return stay
+		}
+		return math.Max(stay, evTable[tp][n])
+	}
+
+	for tp := len(evTable) - 1; tp >= 0; tp-- {
+		for n := 1; n <= 6; n++ {
+			ev := 0.0
+			for _, r := range Rolls(n) {
+				best := 0.0
+				for _, o := range scoringOptions()[r.Dice] {
+					left := n - len(o.DieValues)
+					if left == 0 {
+						left = 6
+					}
+					best = math.Max(best, bestAfter(tp+o.Points/50, left))
+				}
+				ev += r.Probability * best
+			}
+			evTable[tp][n] = ev
+		}
+	}
+}
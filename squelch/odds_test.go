@@ -0,0 +1,108 @@
+package squelch
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRolls_Probabilities(t *testing.T) {
+	for n, want := range map[int]int{1: 6, 2: 21, 3: 56, 4: 126, 5: 252, 6: 462} {
+		rolls := Rolls(n)
+		if got := len(rolls); want != got {
+			t.Errorf("%v dice: distinct rolls incorrect, want %v got %v", n, want, got)
+		}
+
+		sum := 0.0
+		for _, r := range rolls {
+			sum += r.Probability
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("%v dice: probabilities sum to %v", n, sum)
+		}
+	}
+
+	if want, got := 1.0/46656, Rolls(6)[0].Probability; want != got {
+		t.Errorf("111111 probability incorrect, want %v got %v", want, got)
+	}
+}
+
+func TestSquelchProbability(t *testing.T) {
+	// only 4/6 faces miss with one die, and 1/43.2 of six dice rolls miss
+	if want, got := 4.0/6, SquelchProbability(1); math.Abs(want-got) > 1e-9 {
+		t.Errorf("1 die incorrect, want %v got %v", want, got)
+	}
+	if want, got := 1080.0/46656, SquelchProbability(6); math.Abs(want-got) > 1e-9 {
+		t.Errorf("6 dice incorrect, want %v got %v", want, got)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	pos, err := Analyze(6, 0)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if !pos.ShouldRoll() || pos.BestEV != pos.RollEV {
+		t.Errorf("should always roll six dice with nothing at risk, got %+v", pos)
+	}
+
+	if pos, _ := Analyze(1, 2000); pos.ShouldRoll() {
+		t.Errorf("should not risk 2000 points on one die, got %+v", pos)
+	}
+}
+
+func TestAnalyze_Invalid(t *testing.T) {
+	tests := []struct {
+		diceCount, turnPoints int
+	}{
+		{0, 0},
+		{7, 0},
+		{-1, 0},
+		{6, -100},
+		{6, 125},
+	}
+
+	for _, tt := range tests {
+		if _, err := Analyze(tt.diceCount, tt.turnPoints); err == nil {
+			t.Errorf("%v dice with %v points should fail", tt.diceCount, tt.turnPoints)
+		}
+	}
+
+	if _, err := AnalyzeRoll("11256", -100); err == nil {
+		t.Errorf("Negative turn points should fail")
+	}
+	if _, err := AnalyzeRoll("11256", 75); err == nil {
+		t.Errorf("Turn points that aren't a multiple of 50 should fail")
+	}
+	if _, err := AnalyzeRoll("", 0); err == nil {
+		t.Errorf("No dice should fail")
+	}
+	if _, err := AnalyzeRoll("1112345", 0); err == nil {
+		t.Errorf("Seven dice should fail")
+	}
+	if Rolls(0) != nil || Rolls(7) != nil || SquelchProbability(7) != 0 {
+		t.Errorf("Rolls of other than 1 to 6 dice should be empty")
+	}
+}
+
+func TestAnalyzeRoll(t *testing.T) {
+	opts, err := AnalyzeRoll("11256", 300)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := 5, len(opts); want != got {
+		t.Fatalf("Option count incorrect, want %v got %v", want, got)
+	}
+
+	// taking everything leaves 2 dice, not worth rolling with 550 at risk
+	if o := opts[0]; o.DieValues != "115" || o.DiceLeft != 2 || !o.Stay || o.EV != 550 {
+		t.Errorf("First option incorrect, got %+v", o)
+	}
+
+	if opts, _ := AnalyzeRoll("223366", 0); opts[0].DiceLeft != 6 {
+		t.Errorf("Triple double should roll over, got %+v", opts[0])
+	}
+
+	if opts, _ := AnalyzeRoll("223466", 0); len(opts) != 0 {
+		t.Errorf("Squelch should have no options, got %v", len(opts))
+	}
+}
@@ -4,38 +4,135 @@ package squelch
 type Player interface {
 	Info() (*PlayerInfo, error)
 
-	MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string) error
+	MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []Handicap) error
 	MatchEnd(matchID string, winsByBotIndex []int) error
 
 	GameStart(matchID, gameID string) error
 	GameEnd(matchID, gameID string, finalPlayerTurns []PlayerTurn, winnerBotIndex int) error
 
-	TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []PlayerTurn, isFinalRound bool) error
-	Choose(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error)
+	TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []PlayerTurn, isFinalRound bool, state GameState) error
+	Choose(matchID, gameID, turnID string, dieValues string, options []ScoringOption, state GameState) (*PlayerChoice, error)
 	Squelch(matchID, gameID, turnID string, dieValues string) error
 }
 
+// Handicap evens out a match between bots of different strengths, the zero
+// value is no handicap
+type Handicap struct {
+	// StartScore is the score the player starts each game with
+	StartScore int `json:"startScore,omitempty"`
+	// TargetReduction lowers the score the player needs to start the final
+	// round
+	TargetReduction int `json:"targetReduction,omitempty"`
+	// Rerolls are how many squelches a game the player rolls again instead
+	// of losing the turn
+	Rerolls int `json:"rerolls,omitempty"`
+}
+
+// GameState is the whole game as it stands when a player has to act, so a bot
+// doesn't have to track the game itself
+type GameState struct {
+	// BotIndex is the player whose turn it is
+	BotIndex int `json:"botIndex"`
+	// Scores are the banked scores by bot index
+	Scores []int `json:"scores"`
+	// TurnOrder is the bot indexes in the order they play, starting with the
+	// game's first player
+	TurnOrder []int `json:"turnOrder"`
+	// TargetScore is the game's target before handicaps
+	TargetScore int `json:"targetScore"`
+	// Targets are the scores that start the final round by bot index, the
+	// TargetScore lowered by each player's handicap
+	Targets []int `json:"targets"`
+	// TurnNumber counts the turns of the game from 1
+	TurnNumber int `json:"turnNumber"`
+	// TurnPoints are the points taken so far this turn, 0 at the turn start
+	TurnPoints int `json:"turnPoints"`
+	// RerollsLeft are the player's handicap rerolls left this game
+	RerollsLeft int `json:"rerollsLeft,omitempty"`
+	// Rolls are the player's rolls so far this turn
+	Rolls        []PlayerRoll `json:"rolls"`
+	IsFinalRound bool         `json:"isFinalRound"`
+	// OvertimeBotIndex is the player who reached the target score and started
+	// the final round, -1 before then
+	OvertimeBotIndex int `json:"overtimeBotIndex"`
+
+	// Teams are the team of each bot index in a team game, where teammates'
+	// scores count together toward the target. Not set otherwise.
+	Teams []int `json:"teams,omitempty"`
+	// TeamScores are the total scores by team
+	TeamScores []int `json:"teamScores,omitempty"`
+}
+
+// Score is the player's banked score
+func (s GameState) Score() int {
+	if s.BotIndex < 0 || s.BotIndex >= len(s.Scores) {
+		return 0
+	}
+	return s.Scores[s.BotIndex]
+}
+
+// Target is the score the player needs to start the final round
+func (s GameState) Target() int {
+	if s.BotIndex < 0 || s.BotIndex >= len(s.Targets) {
+		return s.TargetScore
+	}
+	return s.Targets[s.BotIndex]
+}
+
+// TeamScore is the player's team's total score, or the player's score if
+// it's not a team game
+func (s GameState) TeamScore() int {
+	if s.Teams == nil || s.BotIndex < 0 || s.BotIndex >= len(s.Teams) {
+		return s.Score()
+	}
+	return s.TeamScores[s.Teams[s.BotIndex]]
+}
+
+// HighScore is the highest banked score of the other players, or of the other
+// teams in a team game
+func (s GameState) HighScore() int {
+	high := 0
+	if s.Teams != nil && s.BotIndex >= 0 && s.BotIndex < len(s.Teams) {
+		for t, sc := range s.TeamScores {
+			if t != s.Teams[s.BotIndex] && sc > high {
+				high = sc
+			}
+		}
+		return high
+	}
+
+	for i, sc := range s.Scores {
+		if i != s.BotIndex && sc > high {
+			high = sc
+		}
+	}
+	return high
+}
+
 // PlayerTurn is a catalog of the turn choices made by a player
 type PlayerTurn struct {
-	BotIndex               int
-	StartPoints, EndPoints int
-	Rolls                  []PlayerRoll
+	BotIndex    int          `json:"botIndex"`
+	StartPoints int          `json:"startPoints"`
+	EndPoints   int          `json:"endPoints"`
+	Rolls       []PlayerRoll `json:"rolls"`
 }
 
 // PlayerRoll is a single roll and selection made by a player
 type PlayerRoll struct {
-	DieValues string
-	Take      string
-	Points    int
+	DieValues string `json:"dieValues"`
+	Take      string `json:"take"`
+	Points    int    `json:"points"`
 }
 
 // PlayerInfo is the basic information about a player
 type PlayerInfo struct {
-	Name string
+	Name string `json:"name"`
+	// Version is optional, it tells results of different builds of a bot apart
+	Version string `json:"version,omitempty"`
 }
 
 // PlayerChoice is the option selected after a roll and if the player wants to keep rolling
 type PlayerChoice struct {
-	TakeOptionID string
-	Stay         bool
+	TakeOptionID string `json:"takeOptionId"`
+	Stay         bool   `json:"stay"`
 }
@@ -18,11 +18,11 @@ func NewMockPlayer(name string, chooseFn chooseFunc) *MockPlayer {
 }
 
 func (p *MockPlayer) Info() (*PlayerInfo, error) {
-	return &PlayerInfo{p.name}, nil
+	return &PlayerInfo{Name: p.name}, nil
 }
 
-func (p *MockPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string) error {
-	ret := p.Mock.Called(matchID, dieCount, maxPoints, gameCount, yourBotIndex, botNames)
+func (p *MockPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []Handicap) error {
+	ret := p.Mock.Called(matchID, dieCount, maxPoints, gameCount, yourBotIndex, botNames, handicaps)
 	return ret.Error(0)
 }
 
@@ -41,14 +41,14 @@ func (p *MockPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []PlayerTu
 	return ret.Error(0)
 }
 
-func (p *MockPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []PlayerTurn, isFinalRound bool) error {
-	ret := p.Mock.Called(matchID, gameID, turnID, startPoints, otherPlayerTurns, isFinalRound)
+func (p *MockPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []PlayerTurn, isFinalRound bool, state GameState) error {
+	ret := p.Mock.Called(matchID, gameID, turnID, startPoints, otherPlayerTurns, isFinalRound, state)
 	return ret.Error(0)
 }
 
-func (p *MockPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+func (p *MockPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []ScoringOption, state GameState) (*PlayerChoice, error) {
 	// document the func was called for asserts
-	p.Mock.Called(matchID, gameID, turnID, dieValues, options)
+	p.Mock.Called(matchID, gameID, turnID, dieValues, options, state)
 	return p.chooseFn(matchID, gameID, turnID, dieValues, options)
 }
 
@@ -0,0 +1,197 @@
+package squelch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// HealthReport is the result of a preflight check of a single entrant
+type HealthReport struct {
+	EntrantIndex int
+	Name         string
+	// Latency is how long the Info ping took
+	Latency time.Duration
+	// GameDuration is how long the scripted dummy game took
+	GameDuration time.Duration
+	Err          error
+}
+
+// Healthy is true if the entrant passed every preflight check
+func (h HealthReport) Healthy() bool {
+	return h.Err == nil
+}
+
+// Preflight pings every entrant and plays a short scripted game with each to
+// confirm it answers Choose with valid option IDs. Entrants are checked
+// concurrently and the reports are returned in entrant order.
+func (t *Tournament) Preflight() []HealthReport {
+	reports := make([]HealthReport, len(t.entrants))
+
+	wg := sync.WaitGroup{}
+	for i, p := range t.entrants {
+		wg.Add(1)
+		go func(i int, p Player) {
+			defer wg.Done()
+			reports[i] = checkPlayer(p, t.targetScore)
+			reports[i].EntrantIndex = i
+		}(i, p)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+// DropUnhealthy removes every entrant with a failed report from the tournament
+// and returns the number removed. Reports must come from Preflight on this
+// tournament.
+func (t *Tournament) DropUnhealthy(reports []HealthReport) int {
+	entrants := t.entrants[:0:0]
+	for _, r := range reports {
+		if r.Healthy() {
+			entrants = append(entrants, t.entrants[r.EntrantIndex])
+		}
+	}
+
+	dropped := len(t.entrants) - len(entrants)
+	t.entrants = entrants
+	t.SetPlayersPerMatch(t.playersPerMatch)
+
+	return dropped
+}
+
+// SetPlayersPerMatch changes how many entrants play in each match, e.g. to
+// keep every entrant in every match after DropUnhealthy. There are no
+// matches if it's more than the entrant count.
+func (t *Tournament) SetPlayersPerMatch(ppm int) {
+	t.playersPerMatch = ppm
+	if ppm <= len(t.entrants) {
+		t.matchCount = calcMatchCount(len(t.entrants), ppm)
+	} else {
+		t.matchCount = 0
+	}
+}
+
+func checkPlayer(p Player, targetScore int) HealthReport {
+	r := HealthReport{}
+
+	start := time.Now()
+	info, err := p.Info()
+	r.Latency = time.Since(start)
+	if err != nil {
+		r.Err = fmt.Errorf("info: %v", err)
+		return r
+	}
+	if info == nil || info.Name == "" {
+		r.Err = fmt.Errorf("info: no name returned")
+		return r
+	}
+	r.Name = info.Name
+
+	start = time.Now()
+	r.Err = playDummyGame(p, info.Name, targetScore)
+	r.GameDuration = time.Since(start)
+
+	return r
+}
+
+// playDummyGame drives a player through a scripted two turn game: a regular
+// turn and a final round turn, each squelching on the third roll if the
+// player hasn't stayed by then.
+func playDummyGame(p Player, name string, targetScore int) error {
+	matchID := "preflight-" + ksuid.New().String()
+	gameID := "1"
+
+	if err := p.MatchStart(matchID, 6, targetScore, 1, 0, []string{name}, nil); err != nil {
+		return fmt.Errorf("match start: %v", err)
+	}
+	if err := p.GameStart(matchID, gameID); err != nil {
+		return fmt.Errorf("game start: %v", err)
+	}
+
+	turns := []PlayerTurn{}
+	score := 0
+	for turn := 1; turn <= 2; turn++ {
+		turnID := strconv.Itoa(turn)
+		pt := PlayerTurn{StartPoints: score, EndPoints: score}
+		// playing alone, the first turn reaches the target for the final round
+		state := GameState{
+			Scores:           []int{score},
+			TurnOrder:        []int{0},
+			TargetScore:      targetScore,
+			TurnNumber:       turn,
+			IsFinalRound:     turn == 2,
+			OvertimeBotIndex: -1,
+		}
+		if state.IsFinalRound {
+			state.OvertimeBotIndex = 0
+		}
+
+		if err := p.TurnStart(matchID, gameID, turnID, score, nil, turn == 2, state); err != nil {
+			return fmt.Errorf("turn start: %v", err)
+		}
+
+		diceCount, points, optionCount := 6, 0, 0
+		// every roll scores until the third roll squelches
+		for roll := 1; ; roll++ {
+			if diceCount == 0 {
+				diceCount = 6
+			}
+
+			if roll == 3 {
+				dice := "223466"[:diceCount]
+				pt.Rolls = append(pt.Rolls, PlayerRoll{dice, "", 0})
+				if err := p.Squelch(matchID, gameID, turnID, dice); err != nil {
+					return fmt.Errorf("squelch: %v", err)
+				}
+				break
+			}
+
+			dice := "15"[:diceCount%3]
+			if diceCount >= 3 {
+				dice = "1" + strings.Repeat("2", diceCount-3) + "55"
+			}
+			options := getDiceOptions(optionCount, dice)
+			optionCount += len(options)
+
+			state.TurnPoints = points
+			state.Rolls = append([]PlayerRoll{}, pt.Rolls...)
+			choice, err := p.Choose(matchID, gameID, turnID, dice, options, state)
+			if err != nil {
+				return fmt.Errorf("choose: %v", err)
+			}
+			if choice == nil {
+				return fmt.Errorf("choose: no choice returned")
+			}
+			opt := getOption(options, choice.TakeOptionID)
+			if opt == nil {
+				return fmt.Errorf("choose: invalid option %v for roll %v", choice.TakeOptionID, dice)
+			}
+
+			points += opt.Points
+			pt.Rolls = append(pt.Rolls, PlayerRoll{dice, opt.DieValues, opt.Points})
+			if choice.Stay {
+				score += points
+				pt.EndPoints = score
+				break
+			}
+
+			diceCount -= len(opt.DieValues)
+		}
+
+		turns = append(turns, pt)
+	}
+
+	if err := p.GameEnd(matchID, gameID, turns[len(turns)-1:], 0); err != nil {
+		return fmt.Errorf("game end: %v", err)
+	}
+	if err := p.MatchEnd(matchID, []int{1}); err != nil {
+		return fmt.Errorf("match end: %v", err)
+	}
+
+	return nil
+}
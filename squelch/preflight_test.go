@@ -0,0 +1,47 @@
+package squelch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func getMockPlayerAnyCallback(name string, chooseFn chooseFunc) *MockPlayer {
+	p := NewMockPlayer(name, chooseFn)
+	p.On("MatchStart", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	p.On("MatchEnd", mock.Anything, mock.Anything).Return(nil)
+	p.On("GameStart", mock.Anything, mock.Anything).Return(nil)
+	p.On("GameEnd", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	p.On("TurnStart", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	p.On("Choose", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	p.On("Squelch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	return p
+}
+
+func TestTournament_Preflight(t *testing.T) {
+	good := getMockPlayerAnyCallback("good", func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		// keep rolling with the last option so we see a squelch
+		return &PlayerChoice{options[len(options)-1].ID, false}, nil
+	})
+	bad := getMockPlayerAnyCallback("bad", func(matchID, gameID, turnID string, dieValues string, options []ScoringOption) (*PlayerChoice, error) {
+		return &PlayerChoice{"bogus", true}, nil
+	})
+
+	tn := NewTournament(1, 2, 5000, []Player{good, bad})
+	reports := tn.Preflight()
+
+	if !reports[0].Healthy() {
+		t.Errorf("expected good player to be healthy, got %v", reports[0].Err)
+	}
+	if reports[1].Healthy() {
+		t.Errorf("expected bad player to be unhealthy")
+	}
+	good.AssertCalled(t, "Squelch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	if want, got := 1, tn.DropUnhealthy(reports); want != got {
+		t.Errorf("Dropped incorrect, want %v got %v", want, got)
+	}
+	if want, got := 1, tn.GetEntrantCount(); want != got {
+		t.Errorf("Entrants incorrect, want %v got %v", want, got)
+	}
+}
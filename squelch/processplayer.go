@@ -0,0 +1,176 @@
+package squelch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var _ Player = &ProcessPlayer{}
+
+// ProcessPlayer is a player backed by a bot program that we run. Every
+// callback is a line of JSON written to the program's stdin:
+//
+//	{"callback": "choose", "request": {...}}
+//
+// and the bot answers each with a line of JSON on its stdout. The requests
+// and responses are the same as an ApiPlayer's, a callback without a response
+// is answered with {}, and a failed callback with {"error": "why"}. Calls are
+// made one at a time. Whatever the bot writes to stderr goes to ours.
+//
+// If the program dies or doesn't answer in time it's started again on the
+// next call. SetDecideMode works the same as for an ApiPlayer.
+type ProcessPlayer struct {
+	jsonPlayer
+	command string
+	args    []string
+	timeout time.Duration
+
+	sync sync.Mutex
+	cmd  *exec.Cmd
+	in   io.WriteCloser
+	out  *bufio.Reader
+}
+
+// NewProcessPlayer starts the bot program and returns a player for it
+func NewProcessPlayer(command string, args ...string) (*ProcessPlayer, error) {
+	p := &ProcessPlayer{
+		command: command,
+		args:    args,
+		timeout: 10 * time.Second,
+	}
+	p.jsonPlayer = jsonPlayer{call: p.call, name: filepath.Base(command)}
+
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetTimeout sets how long the bot has to answer each callback, 10 seconds
+// by default
+func (p *ProcessPlayer) SetTimeout(d time.Duration) {
+	p.timeout = d
+}
+
+// Close stops the bot program
+func (p *ProcessPlayer) Close() error {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+	p.stop()
+	return nil
+}
+
+func (p *ProcessPlayer) start() error {
+	cmd := exec.Command(p.command, p.args...)
+	cmd.Stderr = os.Stderr
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %v: %v", p.command, err)
+	}
+
+	p.cmd, p.in, p.out = cmd, in, bufio.NewReader(out)
+	return nil
+}
+
+// stop kills the program, it's no use once a call fails half way
+func (p *ProcessPlayer) stop() {
+	if p.cmd == nil {
+		return
+	}
+	p.in.Close()
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+	p.cmd = nil
+}
+
+type processLine struct {
+	b   []byte
+	err error
+}
+
+// call writes the request line and waits for the bot's answer
+func (p *ProcessPlayer) call(callback string, req, resp interface{}) error {
+	body, err := json.Marshal(struct {
+		Callback string      `json:"callback"`
+		Request  interface{} `json:"request"`
+	}{callback, req})
+	if err != nil {
+		return err
+	}
+
+	p.sync.Lock()
+	defer p.sync.Unlock()
+
+	if p.cmd == nil {
+		if err := p.start(); err != nil {
+			return fmt.Errorf("%v: %w", callback, err)
+		}
+	}
+
+	if _, err := p.in.Write(append(body, '\n')); err != nil {
+		p.stop()
+		return fmt.Errorf("%v: %w", callback, err)
+	}
+
+	read := make(chan processLine, 1)
+	go func(out *bufio.Reader) {
+		b, err := out.ReadBytes('\n')
+		read <- processLine{b, err}
+	}(p.out)
+
+	var line processLine
+	select {
+	case line = <-read:
+	case <-time.After(p.timeout):
+		// killing it ends the read
+		p.stop()
+		<-read
+		return fmt.Errorf("%v: %w", callback, processTimeout{p.timeout})
+	}
+	if line.err != nil {
+		p.stop()
+		return fmt.Errorf("%v: bot exited: %w", callback, line.err)
+	}
+
+	var failed struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(line.b, &failed); err != nil {
+		return fmt.Errorf("%v: invalid response: %v", callback, err)
+	}
+	if failed.Error != "" {
+		return refusedError{fmt.Sprintf("%v: bot error: %v", callback, failed.Error)}
+	}
+
+	if resp == nil {
+		return nil
+	}
+	if err := json.Unmarshal(line.b, resp); err != nil {
+		return fmt.Errorf("%v: invalid response: %v", callback, err)
+	}
+	return nil
+}
+
+// processTimeout is a net.Error so it's counted with the HTTP bot timeouts
+type processTimeout struct {
+	d time.Duration
+}
+
+func (e processTimeout) Error() string   { return fmt.Sprintf("no answer within %v", e.d) }
+func (e processTimeout) Timeout() bool   { return true }
+func (e processTimeout) Temporary() bool { return true }
@@ -0,0 +1,121 @@
+package squelch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary stand in for a bot program
+func TestMain(m *testing.M) {
+	if os.Getenv("SQUELCH_TEST_BOT") == "1" {
+		runTestBot()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runTestBot answers like a bot that always takes the first option and stays
+func runTestBot() {
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		var req struct {
+			Callback string `json:"callback"`
+			Request  struct {
+				MatchID string          `json:"matchId"`
+				Options []ScoringOption `json:"options"`
+			} `json:"request"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			fmt.Printf("{\"error\": %q}\n", err.Error())
+			continue
+		}
+
+		switch req.Callback {
+		case "info":
+			fmt.Println(`{"name": "proc", "version": "1.0"}`)
+		case "choose":
+			b, _ := json.Marshal(PlayerChoice{req.Request.Options[0].ID, true})
+			fmt.Println(string(b))
+		case "squelch":
+			fmt.Println(`{"error": "nope"}`)
+		case "matchend":
+			if req.Request.MatchID == "hang" {
+				time.Sleep(time.Minute)
+			}
+			fmt.Println(`{}`)
+		default:
+			fmt.Println(`{}`)
+		}
+	}
+}
+
+func getTestProcessPlayer(t *testing.T) *ProcessPlayer {
+	t.Setenv("SQUELCH_TEST_BOT", "1")
+	p, err := NewProcessPlayer(os.Args[0])
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestProcessPlayer_Callbacks(t *testing.T) {
+	p := getTestProcessPlayer(t)
+
+	info, err := p.Info()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if info.Name != "proc" || info.Version != "1.0" {
+		t.Errorf("Info incorrect: %+v", info)
+	}
+
+	if err := p.GameStart("m", "g"); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+
+	choice, err := p.Choose("m", "g", "t", "15", []ScoringOption{{ID: "a", DieValues: "1", Points: 100}}, GameState{})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if choice.TakeOptionID != "a" || !choice.Stay {
+		t.Errorf("Choice incorrect: %+v", choice)
+	}
+
+	if err := p.Squelch("m", "g", "t", "234"); err == nil {
+		t.Errorf("Expected the bot's error")
+	}
+}
+
+func TestProcessPlayer_Timeout(t *testing.T) {
+	p := getTestProcessPlayer(t)
+	p.SetTimeout(100 * time.Millisecond)
+
+	err := p.MatchEnd("hang", []int{1, 0})
+	if !isTimeout(err) {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+
+	// it starts over
+	p.SetTimeout(10 * time.Second)
+	if _, err := p.Info(); err != nil {
+		t.Errorf("Error after restart: %v", err)
+	}
+}
+
+func TestProcessPlayer_Game(t *testing.T) {
+	p := getTestProcessPlayer(t)
+
+	tr := NewTournament(2, 2, 500, []Player{p, stayPlayer("b")})
+	r, err := tr.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if r.MatchesFinished != 1 {
+		t.Errorf("Match should finish, got %+v", r)
+	}
+}
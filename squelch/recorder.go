@@ -0,0 +1,58 @@
+package squelch
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Recorder is an Observer that writes every event as a line of JSON so
+// matches can be replayed or analyzed later
+type Recorder struct {
+	EventFunc
+
+	sync sync.Mutex
+	enc  *json.Encoder
+	err  error
+}
+
+// NewRecorder makes a recorder that writes to w
+func NewRecorder(w io.Writer) *Recorder {
+	r := &Recorder{enc: json.NewEncoder(w)}
+	r.EventFunc = r.record
+	return r
+}
+
+func (r *Recorder) record(e GameEvent) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	// keep the first error, the game shouldn't stop for the recording
+	if err := r.enc.Encode(e); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// Err returns the first error writing the recording, if any
+func (r *Recorder) Err() error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+	return r.err
+}
+
+// ReadEvents reads back the events of a recording
+func ReadEvents(rd io.Reader) ([]GameEvent, error) {
+	var events []GameEvent
+	dec := json.NewDecoder(rd)
+	for {
+		var e GameEvent
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+}
@@ -0,0 +1,71 @@
+package squelch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// MatchRunner plays a single match between the entrants, given by entrant
+// index in increasing order, and returns its result. It's how a tournament
+// can hand its matches to somewhere else, e.g. worker processes.
+type MatchRunner func(ctx context.Context, entrants []int) (MatchResult, error)
+
+// SetMatchRunner makes the tournament play every match with r instead of
+// playing it locally. Observers and latency only see local matches.
+func (t *Tournament) SetMatchRunner(r MatchRunner) {
+	t.runner = r
+}
+
+// PlayMatch plays a single match of the tournament locally between the
+// entrants, given by entrant index in increasing order. It returns an error
+// if ctx is canceled before the match finishes.
+func (t *Tournament) PlayMatch(ctx context.Context, entrants []int) (MatchResult, error) {
+	if len(entrants) != t.playersPerMatch {
+		return MatchResult{}, fmt.Errorf("a match needs %v entrants, got %v", t.playersPerMatch, len(entrants))
+	}
+	if !sort.IntsAreSorted(entrants) {
+		return MatchResult{}, fmt.Errorf("entrants %v aren't in increasing order", entrants)
+	}
+	for i, e := range entrants {
+		if e < 0 || e >= len(t.entrants) || (i > 0 && e == entrants[i-1]) {
+			return MatchResult{}, fmt.Errorf("invalid entrants %v", entrants)
+		}
+	}
+	if t.teams != nil {
+		// a team match is two whole teams
+		count := make(map[int]int)
+		for _, e := range entrants {
+			count[t.teamOf[e]]++
+		}
+		for _, n := range count {
+			if n != len(t.teams[0]) {
+				return MatchResult{}, fmt.Errorf("entrants %v aren't two whole teams", entrants)
+			}
+		}
+	}
+
+	entrantNames := make([]string, len(t.entrants))
+	for _, e := range entrants {
+		info, err := t.entrants[e].Info()
+		if err != nil {
+			return MatchResult{}, fmt.Errorf("Error getting info for entrant %v: %v", e, err)
+		}
+		entrantNames[e] = info.Name
+	}
+
+	return t.playMatch(ctx, context.Background(), append([]int(nil), entrants...), entrantNames, newLatencyRecorder(len(t.entrants)))
+}
+
+// check makes sure a result from a MatchRunner is for the match we asked for
+func (r MatchResult) check(entrants []int, entrantNames []string) error {
+	if matchKey(r.Entrants) != matchKey(entrants) || len(r.Names) != len(entrants) || len(r.Wins) != len(entrants) {
+		return fmt.Errorf("result %v isn't for match %v", r.Entrants, entrants)
+	}
+	for i, e := range entrants {
+		if r.Names[i] != entrantNames[e] {
+			return fmt.Errorf("match %v was played by different bots %v", entrants, r.Names)
+		}
+	}
+	return nil
+}
@@ -5,23 +5,27 @@ import (
 	"math/big"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // ScoringOption is a single option for taking points from a roll
 type ScoringOption struct {
-	ID        string
-	DieValues string
-	Points    int
+	ID        string `json:"id"`
+	DieValues string `json:"dieValues"`
+	Points    int    `json:"points"`
 }
 
-//var optionsSync = sync.Once{}
+var optionsSync = sync.Once{}
 var allOptions map[string][]ScoringOption
 
-func init() {
+// scoringOptions are the options of every sorted roll, generated on first
+// use rather than at init so they're logged wherever the logger is set by then
+func scoringOptions() map[string][]ScoringOption {
 	// generate our dice scoring options once
-	//optionsSync.Do(func() {
-	allOptions = getAllOptions()
-	//})
+	optionsSync.Do(func() {
+		allOptions = getAllOptions()
+	})
+	return allOptions
 }
 
 func getAllOptions() map[string][]ScoringOption {
@@ -29,7 +33,7 @@ func getAllOptions() map[string][]ScoringOption {
 	// 11 choose 6 + 10 choose 5 + 9 choose 4 + 8 choose 3 + 7 choose 2 + 6 choose 1
 	list := make(map[string][]ScoringOption, 923)
 
-	debug("Making options...")
+	logger().Debug("making scoring options")
 	// iterate every 6-dice combo
 
 	for s := range generateCombinations("123456", 6) {
@@ -48,7 +52,7 @@ func getAllOptions() map[string][]ScoringOption {
 		list[s] = score(d)
 	}
 
-	debug("Done")
+	logger().Debug("made scoring options", "rolls", len(list))
 	return list
 }
 
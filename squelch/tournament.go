@@ -1,12 +1,13 @@
 package squelch
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"math/rand"
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/segmentio/ksuid"
 )
@@ -17,6 +18,22 @@ type Tournament struct {
 	playersPerMatch int
 	targetScore     int
 	entrants        []Player
+	observers       []Observer
+	checkpoint      *checkpointWriter
+	resumed         map[string]MatchResult
+	resumedOrder    []MatchResult
+	grace           time.Duration
+	runner          MatchRunner
+	concurrency     int
+	// teams are the entrant indexes of each team in a team tournament, and
+	// teamOf is the team of each entrant
+	teams  [][]int
+	teamOf []int
+	// handicaps are by entrant index, nil for none
+	handicaps []Handicap
+	// rand is the dice and seating source if set, shared by every match
+	rand     *rand.Rand
+	randSync *sync.Mutex
 }
 
 func NewTournament(gpm, ppm, targetScore int, entrants []Player) *Tournament {
@@ -29,19 +46,161 @@ func NewTournament(gpm, ppm, targetScore int, entrants []Player) *Tournament {
 	}
 }
 
+// AddObserver registers an observer for every event of every match in the
+// tournament. Matches run concurrently so the observer must be safe for
+// concurrent use.
+func (t *Tournament) AddObserver(o Observer) {
+	t.observers = append(t.observers, o)
+}
+
+// SetGracePeriod sets how long RunContext waits for games in progress once
+// it's canceled before giving up on them. By default it waits for them all.
+func (t *Tournament) SetGracePeriod(d time.Duration) {
+	t.grace = d
+}
+
+// SetRand makes the dice and seating come from r instead of the global
+// source, so a tournament can be played again from a seed. Only a tournament
+// playing one match at a time repeats exactly.
+func (t *Tournament) SetRand(r *rand.Rand) {
+	t.rand = r
+	t.randSync = &sync.Mutex{}
+}
+
+// perm is a random seating of n players
+func (t *Tournament) perm(n int) []int {
+	if t.rand == nil {
+		return rand.Perm(n)
+	}
+	t.randSync.Lock()
+	defer t.randSync.Unlock()
+	return t.rand.Perm(n)
+}
+
+// roller is the dice roller for the tournament's games, nil for the default
+func (t *Tournament) roller() func(diceCount int) string {
+	if t.rand == nil {
+		return nil
+	}
+	return func(diceCount int) string {
+		t.randSync.Lock()
+		defer t.randSync.Unlock()
+		return rollDiceWith(t.rand.Intn, diceCount)
+	}
+}
+
+// SetConcurrency limits how many matches are played at once. By default
+// every match is played at once.
+func (t *Tournament) SetConcurrency(n int) {
+	t.concurrency = n
+}
+
+// SetTeams makes it a team tournament. Each team is a list of entrant
+// indexes, every entrant is on exactly one team and the teams are the same
+// size. Every team plays every other team, and every player on the team that
+// wins a match gets the point.
+func (t *Tournament) SetTeams(teams [][]int) error {
+	if len(teams) < 2 {
+		return fmt.Errorf("at least 2 teams are required, got %v", len(teams))
+	}
+
+	teamOf := make([]int, len(t.entrants))
+	for i := range teamOf {
+		teamOf[i] = -1
+	}
+	for ti, team := range teams {
+		if len(team) != len(teams[0]) {
+			return fmt.Errorf("teams must be the same size, team %v has %v players and team 0 has %v", ti, len(team), len(teams[0]))
+		}
+		for _, e := range team {
+			if e < 0 || e >= len(t.entrants) {
+				return fmt.Errorf("team %v has an invalid entrant %v", ti, e)
+			}
+			if teamOf[e] != -1 {
+				return fmt.Errorf("entrant %v is on more than one team", e)
+			}
+			teamOf[e] = ti
+		}
+	}
+	for e, ti := range teamOf {
+		if ti == -1 {
+			return fmt.Errorf("entrant %v isn't on a team", e)
+		}
+	}
+	if err := checkTeamHandicaps(t.handicaps); err != nil {
+		return err
+	}
+
+	t.teams, t.teamOf = teams, teamOf
+	t.playersPerMatch = 2 * len(teams[0])
+	t.matchCount = calcMatchCount(len(teams), 2)
+	return nil
+}
+
+// SetHandicaps gives each entrant, by index, a handicap in every game they
+// play. Bots are told every player's handicap when a match starts. Team
+// tournaments can't have target reductions.
+func (t *Tournament) SetHandicaps(handicaps []Handicap) error {
+	if len(handicaps) != len(t.entrants) {
+		return fmt.Errorf("%v handicaps for %v entrants", len(handicaps), len(t.entrants))
+	}
+	if t.teams != nil {
+		if err := checkTeamHandicaps(handicaps); err != nil {
+			return err
+		}
+	}
+	t.handicaps = handicaps
+	return nil
+}
+
+// checkTeamHandicaps refuses target reductions in a team tournament. A team
+// reaches the target with its combined score, so lowering it for one player
+// would make the team's target depend on which teammate stays.
+func checkTeamHandicaps(handicaps []Handicap) error {
+	for e, h := range handicaps {
+		if h.TargetReduction != 0 {
+			return fmt.Errorf("entrant %v has a target reduction, which team tournaments don't support", e)
+		}
+	}
+	return nil
+}
+
 // GetMatchCount returns the number of matches that need to be played total for
 // every player to play every other player an even number of times.
 func (t Tournament) GetMatchCount() int {
 	return t.matchCount
 }
 
+// GetEntrantCount returns the number of entrants playing in the tournament.
+func (t Tournament) GetEntrantCount() int {
+	return len(t.entrants)
+}
+
+// Run plays every match of the tournament and returns the results
 func (t *Tournament) Run() (*Results, error) {
+	return t.RunContext(context.Background())
+}
+
+// RunContext plays the tournament until it's done or ctx is canceled. Once
+// canceled no new games start, the games in progress finish (or are given up
+// on after the grace period) and the results of the matches that finished
+// are returned, marked Incomplete. Unfinished matches aren't counted or
+// checkpointed, so they're played again on resume.
+func (t *Tournament) RunContext(ctx context.Context) (*Results, error) {
 	ranks := struct {
 		sync.Mutex
-		r []Points
+		r        []Points
+		finished int
+		// closed stops late matches changing the results once we've returned
+		closed bool
 	}{r: make([]Points, len(t.entrants))}
 
 	wg := sync.WaitGroup{}
+	lat := newLatencyRecorder(len(t.entrants))
+
+	// abort stops the games in progress once we've given up on them
+	abort, abortGames := context.WithCancel(context.Background())
+	defer abortGames()
 
 	entrantNames := make([]string, len(t.entrants))
 	for i, p := range t.entrants {
@@ -54,80 +213,287 @@ func (t *Tournament) Run() (*Results, error) {
 		entrantNames[i] = info.Name
 	}
 
+	if err := t.validateResumed(entrantNames); err != nil {
+		return nil, err
+	}
+
+	// count a finished match, wins are in entrant order
+	addResult := func(res MatchResult) {
+		ranks.Lock()
+		defer ranks.Unlock()
+
+		if ranks.closed {
+			return
+		}
+		ranks.finished++
+
+		// player (or team) with the most wins gets the match
+		winner, highScore := 0, 0
+		counted := make(map[int]bool)
+		for i, entrantIdx := range res.Entrants {
+			// sum up wins
+			ranks.r[entrantIdx].TotalWins += res.Wins[i]
+			ranks.r[entrantIdx].Matches++
+
+			// teammates share their wins, count the team once
+			side := t.side(entrantIdx)
+			if counted[side] {
+				continue
+			}
+			counted[side] = true
+
+			// find highest score for points
+			if res.Wins[i] > highScore {
+				winner, highScore = side, res.Wins[i]
+			} else if res.Wins[i] == highScore {
+				// a tie -- nobody gets points
+				winner = -1
+			}
+		}
+
+		//no points for ties
+		if winner > -1 {
+			// 1 point for the winner, 0 for losers
+			for _, entrantIdx := range res.Entrants {
+				if t.side(entrantIdx) == winner {
+					ranks.r[entrantIdx].Points++
+				}
+			}
+		}
+	}
+
+	lat.names = entrantNames
+	for _, o := range t.observers {
+		if h, ok := o.(CallbackObserver); ok {
+			lat.hooks = append(lat.hooks, h)
+		}
+	}
+
+	// slots limits the matches in progress, if set
+	var slots chan struct{}
+	if t.concurrency > 0 {
+		slots = make(chan struct{}, t.concurrency)
+	}
+
 	// run full round-robin tournament with the entrants based on the
 	// number of players in each game
-	comb(len(t.entrants), t.playersPerMatch, func(players []int) {
+	t.schedule(func(players []int) {
+		if res, ok := t.resumed[matchKey(players)]; ok {
+			// already played before we were interrupted
+			addResult(res)
+			return
+		}
+
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
 		wg.Add(1)
 
 		go func(players []int) {
 			defer wg.Done()
-			// make a match from the set of players
-			p := make([]Player, t.playersPerMatch)
-
-			// randomize our incoming player order and make a map
-			plMap := rand.Perm(len(players))
-			plNames := make([]string, len(players))
-
-			for i := 0; i < len(plMap); i++ {
-				p[i] = t.entrants[players[plMap[i]]]
-				plNames[i] = entrantNames[players[plMap[i]]]
-			}
-
-			m := &match{
-				players:      p,
-				targetScore:  t.targetScore,
-				gamesInMatch: t.gamesPerMatch,
-				matchID:      ksuid.New().String(),
+			if slots != nil {
+				defer func() { <-slots }()
 			}
 
-			log.Printf("Match %v Start: Players %v", m.matchID, plNames)
-			wins, _ := m.run()
-			log.Printf("Match %v End: Wins %v", m.matchID, wins)
-			// player with the most wins gets the match
-			winner, highScore := 0, 0
-			for i := 0; i < len(wins); i++ {
-				// lookup our match index into the comb index into the entrant index
-				entrantIdx := players[plMap[i]]
-				// sum up wins
-				ranks.Lock()
-				ranks.r[entrantIdx].TotalWins += wins[i]
-				ranks.r[entrantIdx].Matches++
-				ranks.Unlock()
-
-				// find highest score for points
-				if wins[i] > highScore {
-					winner, highScore = entrantIdx, wins[i]
-				} else if wins[i] == highScore {
-					// a tie -- nobody gets points
-					winner = -1
+			var res MatchResult
+			var err error
+			if t.runner != nil {
+				res, err = t.runner(ctx, players)
+				if err == nil {
+					err = res.check(players, entrantNames)
 				}
+			} else {
+				res, err = t.playMatch(ctx, abort, players, entrantNames, lat)
+			}
+			if err != nil {
+				logger().Warn("match not counted", "entrants", players, "err", err)
+				return
 			}
 
-			//no points for ties
-			if winner > -1 {
-				// 1 point for the winner, 0 for losers
-				ranks.Lock()
-				ranks.r[winner].Points++
-				ranks.Unlock()
+			addResult(res)
+			if t.checkpoint != nil {
+				t.checkpoint.write(res)
 			}
 		}(append([]int(nil), players...))
 		// we need to pass in a clone of players
 	})
 
 	// wait for all matches to end
-	wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		var giveUp <-chan time.Time
+		if t.grace > 0 {
+			giveUp = time.After(t.grace)
+		}
+		select {
+		case <-done:
+		case <-giveUp:
+			logger().Warn("gave up on games in progress", "grace", t.grace)
+			abortGames()
+		}
+	}
+
+	ranks.Lock()
+	ranks.closed = true
+	points := append([]Points(nil), ranks.r...)
+	finished := ranks.finished
+	ranks.Unlock()
+	if t.checkpoint != nil {
+		// matches given up on can still finish, they mustn't be checkpointed
+		t.checkpoint.close()
+	}
 
 	// sort the points and return
-	sort.Slice(ranks.r, func(i, j int) bool {
+	sort.Slice(points, func(i, j int) bool {
 		// backwards "less" so we sort high to low
-		if ranks.r[i].Points == ranks.r[j].Points {
-			return ranks.r[j].TotalWins < ranks.r[i].TotalWins
+		if points[i].Points == points[j].Points {
+			return points[j].TotalWins < points[i].TotalWins
 		}
 
-		return ranks.r[j].Points < ranks.r[i].Points
+		return points[j].Points < points[i].Points
 	})
 
-	return &Results{Points: ranks.r, EntrantNames: entrantNames}, nil
+	return &Results{
+		Points:          points,
+		EntrantNames:    entrantNames,
+		Teams:           t.teams,
+		Handicaps:       t.handicaps,
+		Latency:         lat.stats(),
+		MatchesFinished: finished,
+		Incomplete:      finished < t.matchCount,
+	}, nil
+}
+
+// schedule emits the entrants of every match in increasing order, every team
+// against every other team in a team tournament
+func (t *Tournament) schedule(emit func([]int)) {
+	if t.teams == nil {
+		comb(len(t.entrants), t.playersPerMatch, emit)
+		return
+	}
+
+	comb(len(t.teams), 2, func(teams []int) {
+		var players []int
+		for _, ti := range teams {
+			players = append(players, t.teams[ti]...)
+		}
+		sort.Ints(players)
+		emit(players)
+	})
+}
+
+// side is who an entrant plays for, their team in a team tournament
+func (t *Tournament) side(entrantIdx int) int {
+	if t.teamOf == nil {
+		return entrantIdx
+	}
+	return t.teamOf[entrantIdx]
+}
+
+// playMatch plays a single match between the entrants locally. Once ctx is
+// done no new games start, once abort is done the game in progress stops.
+func (t *Tournament) playMatch(ctx, abort context.Context, players []int, entrantNames []string, lat *latencyRecorder) (MatchResult, error) {
+	// make a match from the set of players
+	p := make([]Player, len(players))
+
+	// randomize our incoming player order and make a map
+	plMap := t.perm(len(players))
+	var seatTeams []int
+	if t.teams != nil {
+		plMap, seatTeams = t.seatTeams(players)
+	}
+	plNames := make([]string, len(players))
+	var handicaps []Handicap
+	if t.handicaps != nil {
+		handicaps = make([]Handicap, len(players))
+	}
+
+	for i := 0; i < len(plMap); i++ {
+		// time every call the match and its games make to the entrant
+		p[i] = &timedPlayer{
+			Player:     t.entrants[players[plMap[i]]],
+			entrantIdx: players[plMap[i]],
+			rec:        lat,
+		}
+		plNames[i] = entrantNames[players[plMap[i]]]
+		if handicaps != nil {
+			handicaps[i] = t.handicaps[players[plMap[i]]]
+		}
+	}
+
+	m := &match{
+		players:      p,
+		teams:        seatTeams,
+		handicaps:    handicaps,
+		playerNames:  plNames,
+		targetScore:  t.targetScore,
+		gamesInMatch: t.gamesPerMatch,
+		matchID:      ksuid.New().String(),
+		observers:    t.observers,
+		roll:         t.roller(),
+		ctx:          ctx,
+		abort:        abort,
+	}
+
+	logger().Info("match start", "match", m.matchID, "players", plNames)
+	wins, errs := m.run()
+	logger().Info("match end", "match", m.matchID, "wins", wins)
+	if m.stopped || m.nextGameNumber < m.gamesInMatch {
+		return MatchResult{}, fmt.Errorf("match canceled after %v games", m.nextGameNumber)
+	}
+
+	// lookup our match index into the comb index
+	res := MatchResult{Entrants: players, Names: make([]string, len(players)), Wins: make([]int, len(players)), Errors: make([]int, len(players))}
+	for i := range players {
+		res.Names[i] = entrantNames[players[i]]
+		res.Wins[plMap[i]] = wins[i]
+		res.Errors[plMap[i]] = errs[i]
+	}
+
+	return res, nil
+}
+
+// seatTeams seats the players of a team match so the teams take turns, in a
+// random order within each team. It returns the map of seat to index in
+// players and the team of each seat, numbered from 0 for the match.
+func (t *Tournament) seatTeams(players []int) (plMap, seatTeams []int) {
+	// the match's teams, in random order, with their players shuffled
+	var sides [][]int
+	sideOf := make(map[int]int)
+	for _, i := range t.perm(len(players)) {
+		team := t.teamOf[players[i]]
+		s, ok := sideOf[team]
+		if !ok {
+			s = len(sides)
+			sideOf[team] = s
+			sides = append(sides, nil)
+		}
+		sides[s] = append(sides[s], i)
+	}
+
+	for seat := 0; len(plMap) < len(players); seat++ {
+		for s, side := range sides {
+			if seat < len(side) {
+				plMap = append(plMap, side[seat])
+				seatTeams = append(seatTeams, s)
+			}
+		}
+	}
+	return plMap, seatTeams
 }
 
 // emit all combinations of size m from set [0..n)
@@ -150,13 +516,24 @@ func comb(n, m int, emit func([]int)) {
 }
 
 type match struct {
-	players             []Player
+	players []Player
+	// teams are the team of each player in a team match
+	teams []int
+	// handicaps are by player, nil for none
+	handicaps           []Handicap
 	playerNames         []string
 	targetScore         int
 	gamesInMatch        int
 	matchID             string
 	nextGameNumber      int
 	startingPlayerIndex int
+	observers           []Observer
+	// roll replaces the games' dice roller if set
+	roll func(diceCount int) string
+	ctx  context.Context
+	// abort stops the game in progress, stopped is set if it did
+	abort   context.Context
+	stopped bool
 }
 
 // Run is going to do blah
@@ -166,16 +543,23 @@ func (m *match) run() (wins, errs []int) {
 	wins = make([]int, pc)
 	errs = make([]int, pc)
 
+	notify(m.observers, GameEvent{Type: EventMatchStart, MatchID: m.matchID, Players: m.playerNames})
+
 	// notify all players match begin
 	for i, p := range m.players {
-		err := p.MatchStart(m.matchID, 6, m.targetScore, m.gamesInMatch, i, m.playerNames)
+		err := p.MatchStart(m.matchID, 6, m.targetScore, m.gamesInMatch, i, m.playerNames, m.handicaps)
 		if err != nil {
-			debug("Error on match start: %v\n", err)
+			logger().Warn("match start failed", "match", m.matchID, "player", m.playerNames[i], "err", err)
 			errs[i]++
 		}
 	}
 
 	for i := 0; i < m.gamesInMatch; i++ {
+		if m.ctx != nil && m.ctx.Err() != nil {
+			// canceled, don't start another game
+			break
+		}
+
 		// make a new game
 		m.nextGameNumber++
 		m.startingPlayerIndex++
@@ -183,15 +567,39 @@ func (m *match) run() (wins, errs []int) {
 			m.startingPlayerIndex = 0
 		}
 		g := NewGame(m.players, m.targetScore, m.matchID, strconv.Itoa(m.nextGameNumber), m.startingPlayerIndex)
+		if m.teams != nil {
+			g.SetTeams(m.teams)
+		}
+		if m.handicaps != nil {
+			g.SetHandicaps(m.handicaps)
+		}
+		for _, o := range m.observers {
+			g.AddObserver(o)
+		}
+		if m.abort != nil {
+			g.SetContext(m.abort)
+		}
+		if m.roll != nil {
+			g.SetRollFunc(m.roll)
+		}
 
-		debug("Game %v/%v: Start", m.matchID, g.gameID)
+		g.log.Debug("game start")
 		res, err := g.Run()
-		if err != nil {
-			debug("Game %v/%v: End with Error by player %v:%v", m.matchID, g.gameID, res.ErrIndex, err)
+		if err != nil && res.ErrIndex < 0 {
+			g.log.Warn("game stopped", "err", err)
+			m.stopped = true
+			break
+		} else if err != nil {
+			g.log.Warn("game ended with an error", "player", m.playerNames[res.ErrIndex], "err", err)
 			errs[res.ErrIndex]++
 		} else {
-			debug("Game %v/%v: End with Win by player %v", m.matchID, g.gameID, res.WinnerIndex)
-			wins[res.WinnerIndex]++
+			g.log.Debug("game end", "winner", m.playerNames[res.WinnerIndex])
+			for i := range m.players {
+				// the whole team wins a team game
+				if i == res.WinnerIndex || (m.teams != nil && m.teams[i] == m.teams[res.WinnerIndex]) {
+					wins[i]++
+				}
+			}
 		}
 	}
 
@@ -200,6 +608,8 @@ func (m *match) run() (wins, errs []int) {
 		p.MatchEnd(m.matchID, wins)
 	}
 
+	notify(m.observers, GameEvent{Type: EventMatchEnd, MatchID: m.matchID, Players: m.playerNames, Wins: wins})
+
 	return
 }
 
@@ -233,14 +643,25 @@ func calcMatchCount(playersTotal, ppm int) int {
 }
 
 type Results struct {
-	Points       []Points
-	EntrantNames []string
+	Points       []Points `json:"points"`
+	EntrantNames []string `json:"entrantNames"`
+	// Teams are the entrant indexes of each team in a team tournament
+	Teams [][]int `json:"teams,omitempty"`
+	// Handicaps are by entrant index, if any were given
+	Handicaps []Handicap `json:"handicaps,omitempty"`
+	// Latency is indexed by entrant index
+	Latency []BotLatency `json:"latency"`
+	// MatchesFinished is how many matches were played to the end
+	MatchesFinished int `json:"matchesFinished"`
+	// Incomplete is set when the tournament was canceled before every match
+	// was played
+	Incomplete bool `json:"incomplete"`
 }
 type Points struct {
-	EntrantIndex int
-	Points       int
-	Matches      int
-	TotalWins    int
+	EntrantIndex int `json:"entrantIndex"`
+	Points       int `json:"points"`
+	Matches      int `json:"matches"`
+	TotalWins    int `json:"totalWins"`
 }
 
 func (p Points) String() string {
@@ -0,0 +1,201 @@
+package squelch
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// inFlight counts the matches being played at once
+type inFlight struct {
+	sync      sync.Mutex
+	matches   map[string]bool
+	now, most int
+}
+
+type countingPlayer struct {
+	*MockPlayer
+	c *inFlight
+}
+
+func (p *countingPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []Handicap) error {
+	// only the first player of a match counts it
+	if yourBotIndex == 0 {
+		p.c.sync.Lock()
+		p.c.matches[matchID] = true
+		p.c.now++
+		if p.c.now > p.c.most {
+			p.c.most = p.c.now
+		}
+		p.c.sync.Unlock()
+		// give the other matches a chance to start
+		time.Sleep(10 * time.Millisecond)
+	}
+	return p.MockPlayer.MatchStart(matchID, dieCount, maxPoints, gameCount, yourBotIndex, botNames, handicaps)
+}
+
+func (p *countingPlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	p.c.sync.Lock()
+	if p.c.matches[matchID] {
+		delete(p.c.matches, matchID)
+		p.c.now--
+	}
+	p.c.sync.Unlock()
+	return p.MockPlayer.MatchEnd(matchID, winsByBotIndex)
+}
+
+func TestTournament_Concurrency(t *testing.T) {
+	c := &inFlight{matches: make(map[string]bool)}
+	var players []Player
+	for _, n := range []string{"a", "b", "c", "d", "e"} {
+		players = append(players, &countingPlayer{stayPlayer(n), c})
+	}
+
+	tr := NewTournament(1, 2, 500, players)
+	tr.SetConcurrency(2)
+	r, err := tr.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if r.MatchesFinished != 10 {
+		t.Errorf("Every match should finish, got %v", r.MatchesFinished)
+	}
+	if c.most > 2 {
+		t.Errorf("At most 2 matches should play at once, got %v", c.most)
+	}
+}
+
+func TestTournament_Teams(t *testing.T) {
+	players := []Player{stayPlayer("a"), stayPlayer("b"), stayPlayer("c"), stayPlayer("d"), stayPlayer("e"), stayPlayer("f")}
+	tr := NewTournament(3, 2, 500, players)
+	if err := tr.SetTeams([][]int{{0, 2}, {1, 3}, {4, 5}}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if want, got := 3, tr.GetMatchCount(); want != got {
+		t.Errorf("Match count incorrect, want %v got %v", want, got)
+	}
+
+	// teammates sit apart so the teams take turns
+	var sync sync.Mutex
+	tr.AddObserver(EventFunc(func(e GameEvent) {
+		if e.Type != EventGameStart {
+			return
+		}
+		sync.Lock()
+		defer sync.Unlock()
+		if len(e.Teams) != 4 || e.Teams[0] != e.Teams[2] || e.Teams[1] != e.Teams[3] || e.Teams[0] == e.Teams[1] {
+			t.Errorf("Seating incorrect: %v %v", e.Players, e.Teams)
+		}
+	}))
+
+	res, err := tr.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// teammates have the same record
+	byEntrant := make(map[int]Points)
+	for _, p := range res.Points {
+		byEntrant[p.EntrantIndex] = p
+	}
+	for _, team := range res.Teams {
+		a, b := byEntrant[team[0]], byEntrant[team[1]]
+		if a.Points != b.Points || a.TotalWins != b.TotalWins || a.Matches != 2 || b.Matches != 2 {
+			t.Errorf("Teammates' records differ: %+v %+v", a, b)
+		}
+	}
+}
+
+func TestTournament_SetTeams_Invalid(t *testing.T) {
+	players := []Player{stayPlayer("a"), stayPlayer("b"), stayPlayer("c"), stayPlayer("d")}
+	for _, teams := range [][][]int{
+		{{0, 1, 2, 3}},
+		{{0, 1, 2}, {3}},
+		{{0, 1}, {1, 2}},
+		{{0, 1}, {2, 4}},
+		{{0}, {1}},
+	} {
+		if err := NewTournament(1, 2, 500, players).SetTeams(teams); err == nil {
+			t.Errorf("%v: expected an error", teams)
+		}
+	}
+}
+
+func TestTournament_Handicaps(t *testing.T) {
+	players := []*MockPlayer{stayPlayer("a"), stayPlayer("b")}
+	tr := NewTournament(1, 2, 500, []Player{players[0], players[1]})
+	handicaps := []Handicap{{StartScore: 100}, {Rerolls: 2}}
+	if err := tr.SetHandicaps(handicaps[:1]); err == nil {
+		t.Errorf("Expected an error for too few handicaps")
+	}
+	if err := tr.SetHandicaps(handicaps); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	res, err := tr.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	assert.Equal(t, handicaps, res.Handicaps)
+
+	// the handicaps are announced in seat order
+	for _, c := range players[0].Calls {
+		if c.Method != "MatchStart" {
+			continue
+		}
+		names := c.Arguments.Get(5).([]string)
+		got := c.Arguments.Get(6).([]Handicap)
+		for i, name := range names {
+			if want := handicaps[name[0]-'a']; got[i] != want {
+				t.Errorf("Handicap for %v incorrect, want %+v got %+v", name, want, got[i])
+			}
+		}
+	}
+}
+
+func TestTournament_TeamTargetReduction(t *testing.T) {
+	players := []Player{stayPlayer("a"), stayPlayer("b"), stayPlayer("c"), stayPlayer("d")}
+	teams := [][]int{{0, 2}, {1, 3}}
+	handicaps := []Handicap{{}, {StartScore: 100}, {TargetReduction: 100}, {}}
+
+	// refused whichever is set first
+	tr := NewTournament(1, 4, 500, players)
+	if err := tr.SetTeams(teams); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := tr.SetHandicaps(handicaps); err == nil {
+		t.Errorf("Expected an error for a target reduction in a team tournament")
+	}
+
+	tr = NewTournament(1, 4, 500, players)
+	if err := tr.SetHandicaps(handicaps); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := tr.SetTeams(teams); err == nil {
+		t.Errorf("Expected an error for teams with a target reduction")
+	}
+
+	// other handicaps are fine
+	handicaps[2] = Handicap{Rerolls: 1}
+	if err := tr.SetTeams(teams); err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
+
+func TestTournament_SetRand(t *testing.T) {
+	run := func() []Points {
+		tr := NewTournament(10, 2, 1000, []Player{stayPlayer("a"), stayPlayer("b")})
+		tr.SetRand(rand.New(rand.NewSource(3)))
+		r, err := tr.Run()
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		return r.Points
+	}
+
+	assert.Equal(t, run(), run(), "the same seed should play the same games")
+}
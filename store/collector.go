@@ -0,0 +1,146 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"github.com/segmentio/ksuid"
+)
+
+var _ squelch.Observer = &Collector{}
+
+// Collector is a tournament observer that gathers the matches and games for
+// the store. Add it to a Tournament with AddObserver before it runs. Matches
+// that weren't played here, resumed from a checkpoint or played by a worker,
+// are counted in the stats but have no games.
+type Collector struct {
+	sync    sync.Mutex
+	started time.Time
+
+	playing  map[string]*Match
+	finished []Match
+
+	// by bot name
+	turns     map[string]int
+	squelches map[string]int
+	// gameTurns are the turns so far of the games in progress
+	gameTurns map[string]int
+}
+
+// NewCollector makes a collector, the tournament is taken to start now
+func NewCollector() *Collector {
+	return &Collector{
+		started:   time.Now(),
+		playing:   make(map[string]*Match),
+		turns:     make(map[string]int),
+		squelches: make(map[string]int),
+		gameTurns: make(map[string]int),
+	}
+}
+
+func (c *Collector) OnMatchStart(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.playing[e.MatchID] = &Match{ID: e.MatchID, Players: e.Players}
+}
+
+func (c *Collector) OnGameStart(e squelch.GameEvent) {}
+
+func (c *Collector) OnTurnStart(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.turns[e.Players[e.BotIndex]]++
+	c.gameTurns[e.MatchID+"/"+e.GameID]++
+}
+
+func (c *Collector) OnRoll(e squelch.GameEvent) {}
+
+func (c *Collector) OnChoice(e squelch.GameEvent) {}
+
+func (c *Collector) OnSquelch(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.squelches[e.Players[e.BotIndex]]++
+}
+
+func (c *Collector) OnRollover(e squelch.GameEvent) {}
+
+func (c *Collector) OnReroll(e squelch.GameEvent) {}
+
+func (c *Collector) OnOvertime(e squelch.GameEvent) {}
+
+func (c *Collector) OnGameEnd(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	key := e.MatchID + "/" + e.GameID
+	turns := c.gameTurns[key]
+	delete(c.gameTurns, key)
+
+	m, ok := c.playing[e.MatchID]
+	if !ok {
+		return
+	}
+	m.Games = append(m.Games, Game{
+		ID:     e.GameID,
+		Winner: e.WinnerIndex,
+		Scores: append([]int(nil), e.Scores...),
+		Turns:  turns,
+		Err:    e.Err,
+	})
+}
+
+func (c *Collector) OnMatchEnd(e squelch.GameEvent) {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	m, ok := c.playing[e.MatchID]
+	if !ok {
+		return
+	}
+	delete(c.playing, e.MatchID)
+	m.Wins = e.Wins
+	c.finished = append(c.finished, *m)
+}
+
+// Tournament makes the record of a tournament from its settings, results and
+// the matches seen. The caller fills in the entrant versions.
+func (c *Collector) Tournament(gamesPerMatch, playersPerMatch, targetScore int, r *squelch.Results) Tournament {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+
+	t := Tournament{
+		ID:              ksuid.New().String(),
+		Started:         c.started,
+		Finished:        time.Now(),
+		GamesPerMatch:   gamesPerMatch,
+		PlayersPerMatch: playersPerMatch,
+		TargetScore:     targetScore,
+		Incomplete:      r.Incomplete,
+		Handicaps:       r.Handicaps,
+		Entrants:        make([]Entrant, len(r.EntrantNames)),
+		Matches:         append([]Match(nil), c.finished...),
+		Stats:           make([]BotStats, len(r.Points)),
+	}
+	for i, n := range r.EntrantNames {
+		t.Entrants[i] = Entrant{Name: n}
+	}
+
+	// the points are already best first
+	for i, p := range r.Points {
+		name := r.EntrantNames[p.EntrantIndex]
+		t.Stats[i] = BotStats{
+			EntrantIndex: p.EntrantIndex,
+			Rank:         i + 1,
+			Points:       p.Points,
+			Matches:      p.Matches,
+			GameWins:     p.TotalWins,
+			Games:        p.Matches * gamesPerMatch,
+			Turns:        c.turns[name],
+			Squelches:    c.squelches[name],
+		}
+	}
+
+	return t
+}
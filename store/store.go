@@ -0,0 +1,353 @@
+// Package store keeps the results of every tournament in a file so bots can
+// be tracked from run to run. The file is append-only JSON lines, one
+// tournament per line, so it survives crashes and can be read with any tool.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// Tournament is everything recorded about a tournament
+type Tournament struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name,omitempty"`
+	Started         time.Time `json:"started"`
+	Finished        time.Time `json:"finished"`
+	GamesPerMatch   int       `json:"gamesPerMatch"`
+	PlayersPerMatch int       `json:"playersPerMatch"`
+	TargetScore     int       `json:"targetScore"`
+	// Incomplete is set when the tournament was stopped before every match
+	// was played
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Handicaps are by entrant index, if any were given
+	Handicaps []squelch.Handicap `json:"handicaps,omitempty"`
+
+	Entrants []Entrant `json:"entrants"`
+	Matches  []Match   `json:"matches"`
+	// Stats are by entrant index, best first
+	Stats []BotStats `json:"stats"`
+}
+
+// Handicapped is set if any entrant played with a handicap
+func (t Tournament) Handicapped() bool {
+	for _, h := range t.Handicaps {
+		if h != (squelch.Handicap{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// Entrant is a bot as it was when the tournament was played
+type Entrant struct {
+	Name string `json:"name"`
+	// Version is what the bot reported, if anything
+	Version string `json:"version,omitempty"`
+}
+
+// Match is a finished match
+type Match struct {
+	ID string `json:"id"`
+	// Players are the player names by bot index
+	Players []string `json:"players"`
+	// Wins are the game wins by bot index
+	Wins  []int  `json:"wins"`
+	Games []Game `json:"games"`
+}
+
+// Game is a finished game
+type Game struct {
+	ID string `json:"id"`
+	// Winner is the winning bot index, -1 if the game ended with an error
+	Winner int    `json:"winner"`
+	Scores []int  `json:"scores"`
+	Turns  int    `json:"turns"`
+	Err    string `json:"error,omitempty"`
+}
+
+// BotStats is how an entrant did in a tournament
+type BotStats struct {
+	EntrantIndex int `json:"entrantIndex"`
+	Rank         int `json:"rank"`
+	Points       int `json:"points"`
+	Matches      int `json:"matches"`
+	GameWins     int `json:"gameWins"`
+	Games        int `json:"games"`
+	Turns        int `json:"turns"`
+	Squelches    int `json:"squelches"`
+}
+
+// MatchRate is the share of matches won
+func (s BotStats) MatchRate() float64 {
+	return rate(s.Points, s.Matches)
+}
+
+// GameRate is the share of games won
+func (s BotStats) GameRate() float64 {
+	return rate(s.GameWins, s.Games)
+}
+
+func rate(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of)
+}
+
+// Store is a results file
+type Store struct {
+	sync sync.Mutex
+	path string
+}
+
+// Open returns the store in the file at path. The file is created when the
+// first tournament is added.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Add appends a tournament to the store
+func (s *Store) Add(t Tournament) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	s.sync.Lock()
+	defer s.sync.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if torn, err := tornLastLine(f); err != nil {
+		f.Close()
+		return err
+	} else if torn {
+		// start on a line of our own after a crash mid-append
+		b = append([]byte{'\n'}, b...)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// tornLastLine is true if the file doesn't end with a newline
+func tornLastLine(f *os.File) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil || fi.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, fi.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// Tournaments returns every tournament in the store, oldest first. A missing
+// file is an empty store. Lines that can't be read, e.g. one torn by a crash
+// mid-append, are skipped so one bad line doesn't lose the rest, and why is
+// returned in skipped.
+func (s *Store) Tournaments() (ts []Tournament, skipped []error, err error) {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var res []Tournament
+	sc := bufio.NewScanner(f)
+	// a tournament with every game is a long line
+	sc.Buffer(nil, 64*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var t Tournament
+		if err := json.Unmarshal(sc.Bytes(), &t); err != nil {
+			skipped = append(skipped, fmt.Errorf("%v line %v: %v", s.path, line, err))
+			continue
+		}
+		res = append(res, t)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Started.Before(res[j].Started)
+	})
+	return res, skipped, nil
+}
+
+// Get returns the tournament with the id. A unique prefix of the id will do.
+func (s *Store) Get(id string) (*Tournament, error) {
+	ts, _, err := s.Tournaments()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Tournament
+	for i, t := range ts {
+		if t.ID == id {
+			return &ts[i], nil
+		}
+		if strings.HasPrefix(t.ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("more than one tournament starts with %q", id)
+			}
+			found = &ts[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no tournament %q", id)
+	}
+	return found, nil
+}
+
+// Standing is a bot version's record across every tournament it played
+type Standing struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version,omitempty"`
+	Tournaments int       `json:"tournaments"`
+	Firsts      int       `json:"firsts"`
+	Points      int       `json:"points"`
+	Matches     int       `json:"matches"`
+	GameWins    int       `json:"gameWins"`
+	Games       int       `json:"games"`
+	LastPlayed  time.Time `json:"lastPlayed"`
+}
+
+// MatchRate is the share of matches won
+func (s Standing) MatchRate() float64 {
+	return rate(s.Points, s.Matches)
+}
+
+// GameRate is the share of games won
+func (s Standing) GameRate() float64 {
+	return rate(s.GameWins, s.Games)
+}
+
+// Leaderboard totals every bot version over the tournaments, best match win
+// rate first
+func Leaderboard(ts []Tournament) []Standing {
+	byBot := make(map[Entrant]*Standing)
+	var res []*Standing
+
+	for _, t := range ts {
+		for _, st := range t.Stats {
+			if st.EntrantIndex < 0 || st.EntrantIndex >= len(t.Entrants) || st.Matches == 0 {
+				continue
+			}
+			e := t.Entrants[st.EntrantIndex]
+			s, ok := byBot[e]
+			if !ok {
+				s = &Standing{Name: e.Name, Version: e.Version}
+				byBot[e] = s
+				res = append(res, s)
+			}
+
+			s.Tournaments++
+			if st.Rank == 1 {
+				s.Firsts++
+			}
+			s.Points += st.Points
+			s.Matches += st.Matches
+			s.GameWins += st.GameWins
+			s.Games += st.Games
+			if t.Started.After(s.LastPlayed) {
+				s.LastPlayed = t.Started
+			}
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].MatchRate() != res[j].MatchRate() {
+			return res[i].MatchRate() > res[j].MatchRate()
+		}
+		return res[i].GameRate() > res[j].GameRate()
+	})
+
+	out := make([]Standing, len(res))
+	for i, s := range res {
+		out[i] = *s
+	}
+	return out
+}
+
+// AutoHandicaps gives the bots start score handicaps from their leaderboard
+// match win rates, so weaker bots can still compete. The best bot gets none,
+// the rest get up to half the target score by how far behind it they are.
+// Bots that have not played start out like the weakest bot that has. It is
+// nil if no bot has played. Handicapped tournaments are left out, so the
+// handicaps don't feed back into themselves.
+func AutoHandicaps(ts []Tournament, bots []Entrant, targetScore int) []squelch.Handicap {
+	var even []Tournament
+	for _, t := range ts {
+		if !t.Handicapped() {
+			even = append(even, t)
+		}
+	}
+
+	rates := make(map[Entrant]float64)
+	for _, s := range Leaderboard(even) {
+		rates[Entrant{Name: s.Name, Version: s.Version}] = s.MatchRate()
+	}
+
+	known := make([]float64, len(bots))
+	best, worst := -1.0, 2.0
+	for i, b := range bots {
+		r, ok := rates[b]
+		if !ok {
+			known[i] = -1
+			continue
+		}
+		known[i] = r
+		if r > best {
+			best = r
+		}
+		if r < worst {
+			worst = r
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	res := make([]squelch.Handicap, len(bots))
+	for i, r := range known {
+		if r < 0 {
+			r = worst
+		}
+		// a bot that wins no matches against one that wins them all starts
+		// halfway there, rounded to the 50s scores come in
+		start := int(float64(targetScore)*(best-r)/2/50+0.5) * 50
+		if start > targetScore/2 {
+			start = targetScore / 2
+		}
+		res[i].StartScore = start
+	}
+	return res
+}
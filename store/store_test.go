@@ -0,0 +1,207 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func builtin(t *testing.T, name string) squelch.Player {
+	p, err := localbot.NewBuiltinPlayer(name)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return p
+}
+
+func TestCollector_Tournament(t *testing.T) {
+	players := []squelch.Player{builtin(t, "threshold"), builtin(t, "threshold?t=300"), builtin(t, "threshold?t=600")}
+	tr := squelch.NewTournament(3, 2, 1000, players)
+	c := NewCollector()
+	tr.AddObserver(c)
+
+	r, err := tr.Run()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	rec := c.Tournament(3, 2, 1000, r)
+	if len(rec.Entrants) != 3 || rec.Entrants[1].Name != "threshold?t=300" {
+		t.Fatalf("Entrants incorrect: %v", rec.Entrants)
+	}
+	if len(rec.Matches) != 3 {
+		t.Fatalf("Match count incorrect, want 3 got %v", len(rec.Matches))
+	}
+	for _, m := range rec.Matches {
+		if len(m.Games) != 3 || len(m.Wins) != 2 || len(m.Players) != 2 {
+			t.Errorf("Match incomplete: %+v", m)
+		}
+		for _, g := range m.Games {
+			if g.Turns == 0 || len(g.Scores) != 2 || g.Winner < 0 {
+				t.Errorf("Game incomplete: %+v", g)
+			}
+		}
+	}
+
+	for i, s := range rec.Stats {
+		if s.Rank != i+1 {
+			t.Errorf("Rank incorrect, want %v got %v", i+1, s.Rank)
+		}
+		if s.Matches != 2 || s.Games != 6 || s.Turns == 0 {
+			t.Errorf("Stats incorrect: %+v", s)
+		}
+	}
+}
+
+func TestStore_AddGet(t *testing.T) {
+	s := Open(filepath.Join(t.TempDir(), "results.jsonl"))
+
+	ts, _, err := s.Tournaments()
+	if err != nil || len(ts) != 0 {
+		t.Fatalf("Missing file should be empty, got %v %v", ts, err)
+	}
+
+	now := time.Now()
+	if err := s.Add(Tournament{ID: "abc2", Started: now}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := s.Add(Tournament{ID: "abc1", Started: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	ts, _, err = s.Tournaments()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(ts) != 2 || ts[0].ID != "abc1" {
+		t.Fatalf("Tournaments should be oldest first: %v", ts)
+	}
+
+	if got, err := s.Get("abc2"); err != nil || got.ID != "abc2" {
+		t.Errorf("Get by id failed: %v %v", got, err)
+	}
+	if _, err := s.Get("abc"); err == nil {
+		t.Errorf("Ambiguous prefix should fail")
+	}
+	if _, err := s.Get("xyz"); err == nil {
+		t.Errorf("Unknown id should fail")
+	}
+}
+
+func TestStore_CorruptLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s := Open(path)
+	if err := s.Add(Tournament{ID: "good1"}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// a crash mid-append leaves half a line at the end
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	f.WriteString(`{"id":"torn","entr`)
+	f.Close()
+
+	ts, skipped, err := s.Tournaments()
+	if err != nil || len(ts) != 1 || ts[0].ID != "good1" {
+		t.Fatalf("The torn line should be skipped, got %v %v", ts, err)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Error(), "line 2") {
+		t.Errorf("The torn line should be reported, got %v", skipped)
+	}
+
+	// the next tournament starts on its own line
+	if err := s.Add(Tournament{ID: "good2"}); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	ts, _, err = s.Tournaments()
+	if err != nil || len(ts) != 2 || ts[1].ID != "good2" {
+		t.Errorf("Tournaments after the torn line incorrect, got %v %v", ts, err)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	ts := []Tournament{
+		{
+			Entrants: []Entrant{{Name: "a", Version: "1"}, {Name: "b"}},
+			Stats: []BotStats{
+				{EntrantIndex: 1, Rank: 1, Points: 1, Matches: 1, GameWins: 3, Games: 5},
+				{EntrantIndex: 0, Rank: 2, Points: 0, Matches: 1, GameWins: 2, Games: 5},
+			},
+		},
+		{
+			Entrants: []Entrant{{Name: "a", Version: "2"}, {Name: "b"}},
+			Stats: []BotStats{
+				{EntrantIndex: 0, Rank: 1, Points: 1, Matches: 1, GameWins: 4, Games: 5},
+				{EntrantIndex: 1, Rank: 2, Points: 0, Matches: 1, GameWins: 1, Games: 5},
+			},
+		},
+	}
+
+	board := Leaderboard(ts)
+	if len(board) != 3 {
+		t.Fatalf("Each bot version should be ranked, got %v", board)
+	}
+
+	// a@2 and b both won a match, a@2 won more games
+	if board[0].Name != "a" || board[0].Version != "2" {
+		t.Errorf("First incorrect: %+v", board[0])
+	}
+	if board[1].Name != "b" || board[1].Tournaments != 2 || board[1].Firsts != 1 || board[1].GameWins != 4 {
+		t.Errorf("Second incorrect: %+v", board[1])
+	}
+	if board[2].Version != "1" {
+		t.Errorf("Last incorrect: %+v", board[2])
+	}
+}
+
+func TestAutoHandicaps(t *testing.T) {
+	ts := []Tournament{{
+		Entrants: []Entrant{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		Stats: []BotStats{
+			{EntrantIndex: 0, Rank: 1, Points: 4, Matches: 4},
+			{EntrantIndex: 1, Rank: 2, Points: 3, Matches: 4},
+			{EntrantIndex: 2, Rank: 3, Points: 0, Matches: 4},
+		},
+	}}
+
+	h := AutoHandicaps(ts, []Entrant{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "new"}}, 10000)
+	var starts []int
+	for _, x := range h {
+		starts = append(starts, x.StartScore)
+	}
+	// b is a quarter behind a, c and the newcomer are as far behind as it gets
+	if fmt.Sprint(starts) != "[0 1250 5000 5000]" {
+		t.Errorf("Handicaps incorrect: %v", starts)
+	}
+
+	if h := AutoHandicaps(ts, []Entrant{{Name: "x"}, {Name: "y"}}, 10000); h != nil {
+		t.Errorf("Unknown bots should have no handicaps, got %+v", h)
+	}
+
+	// a handicapped tournament where c won everything doesn't count
+	ts = append(ts, Tournament{
+		Handicaps: []squelch.Handicap{{}, {}, {StartScore: 5000}},
+		Entrants:  []Entrant{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		Stats: []BotStats{
+			{EntrantIndex: 2, Rank: 1, Points: 8, Matches: 8},
+			{EntrantIndex: 0, Rank: 2, Points: 0, Matches: 8},
+			{EntrantIndex: 1, Rank: 3, Points: 0, Matches: 8},
+		},
+	})
+	h = AutoHandicaps(ts, []Entrant{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 10000)
+	starts = nil
+	for _, x := range h {
+		starts = append(starts, x.StartScore)
+	}
+	if fmt.Sprint(starts) != "[0 1250 5000]" {
+		t.Errorf("Handicaps should ignore handicapped tournaments: %v", starts)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"github.com/dlclark/squelchbot-arena-go/web"
+)
+
+// runServe serves the browser UI to play the bots and watch them play
+func runServe(args []string) {
+	fs := newFlagSet("serve", "[flags]", "Serve the web UI where people play the -vs bots and watch them play each other.")
+	vs := addVsFlag(fs, "builtin:threshold and builtin:maxev")
+	addr := fs.String("addr", "localhost:8080", "address to serve on")
+	target := fs.Int("target", 5000, "target score")
+	exhibit := fs.Bool("exhibit", false, "keep the bots playing each other for spectators")
+	delay := fs.Duration("delay", 500*time.Millisecond, "pause after each roll and choice while spectators are watching")
+	lf := newLogFlags(fs)
+	fs.Parse(args)
+
+	if err := lf.setup(); err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	if len(*vs) == 0 {
+		*vs = specs{"builtin:threshold", "builtin:maxev"}
+	}
+
+	// make sure every bot can be made before we serve them
+	bots, err := makeBots(*vs, "")
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+
+	s := web.NewServer(*target, *vs, newBot)
+
+	if *exhibit {
+		if len(bots) < 2 {
+			log.Fatalf("Invalid input: at least 2 bots are needed for an exhibition")
+		}
+		go func() {
+			for {
+				t := squelch.NewTournament(1, 2, *target, bots)
+				t.AddObserver(s.Spectate(*delay))
+				t.Run()
+			}
+		}()
+	}
+
+	fmt.Printf("Serving on http://%v\n", *addr)
+	if err := http.ListenAndServe(*addr, s.Handler()); err != nil {
+		fmt.Printf("Error serving: %v\n", err)
+	}
+}
+
+// newBot makes a single bot from its spec for a browser game
+func newBot(spec string) (squelch.Player, error) {
+	p, err := makeBots(specs{spec}, "")
+	if err != nil {
+		return nil, err
+	}
+	return p[0], nil
+}
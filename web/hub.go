@@ -0,0 +1,73 @@
+package web
+
+import (
+	"sync"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// subscriberBuffer is how many events a slow browser can fall behind before
+// it starts missing them
+const subscriberBuffer = 256
+
+// hub fans game events out to every connected browser
+type hub struct {
+	sync sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	// matchID limits the subscriber to a single match, empty for all of them
+	matchID string
+	events  chan squelch.GameEvent
+}
+
+func newHub() *hub {
+	return &hub{subs: make(map[*subscriber]struct{})}
+}
+
+func (h *hub) subscribe(matchID string) *subscriber {
+	s := &subscriber{matchID: matchID, events: make(chan squelch.GameEvent, subscriberBuffer)}
+	h.sync.Lock()
+	h.subs[s] = struct{}{}
+	h.sync.Unlock()
+	return s
+}
+
+func (h *hub) unsubscribe(s *subscriber) {
+	h.sync.Lock()
+	delete(h.subs, s)
+	h.sync.Unlock()
+}
+
+// spectators returns the number of browsers watching every match, leaving
+// out the ones following just their own game
+func (h *hub) spectators() int {
+	h.sync.Lock()
+	defer h.sync.Unlock()
+
+	n := 0
+	for s := range h.subs {
+		if s.matchID == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// publish sends the event to every interested subscriber without blocking
+// the game; subscribers that have fallen too far behind miss it
+func (h *hub) publish(e squelch.GameEvent) {
+	h.sync.Lock()
+	defer h.sync.Unlock()
+
+	for s := range h.subs {
+		if s.matchID != "" && s.matchID != e.MatchID {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+// idleTimeout is how long a browser player has to make a choice before the
+// game is abandoned
+const idleTimeout = 10 * time.Minute
+
+var errAbandoned = errors.New("game abandoned")
+
+var _ squelch.Player = &browserPlayer{}
+
+// browserPlayer is a human playing in the browser. The browser sees the roll
+// through the event stream and posts its choice back.
+type browserPlayer struct {
+	name    string
+	choices chan squelch.PlayerChoice
+	quit    chan struct{}
+	once    sync.Once
+}
+
+func newBrowserPlayer(name string) *browserPlayer {
+	return &browserPlayer{
+		name:    name,
+		choices: make(chan squelch.PlayerChoice),
+		quit:    make(chan struct{}),
+	}
+}
+
+// choose hands the browser's choice to the waiting game. It returns false if
+// the game isn't waiting on the player.
+func (p *browserPlayer) choose(c squelch.PlayerChoice) bool {
+	select {
+	case p.choices <- c:
+		return true
+	default:
+		return false
+	}
+}
+
+// abandon ends the game if it's waiting on the player
+func (p *browserPlayer) abandon() {
+	p.once.Do(func() { close(p.quit) })
+}
+
+func (p *browserPlayer) Info() (*squelch.PlayerInfo, error) {
+	return &squelch.PlayerInfo{Name: p.name}, nil
+}
+
+func (p *browserPlayer) MatchStart(matchID string, dieCount, maxPoints, gameCount, yourBotIndex int, botNames []string, handicaps []squelch.Handicap) error {
+	return nil
+}
+
+func (p *browserPlayer) MatchEnd(matchID string, winsByBotIndex []int) error {
+	return nil
+}
+
+func (p *browserPlayer) GameStart(matchID, gameID string) error {
+	return nil
+}
+
+func (p *browserPlayer) GameEnd(matchID, gameID string, finalPlayerTurns []squelch.PlayerTurn, winnerBotIndex int) error {
+	return nil
+}
+
+func (p *browserPlayer) TurnStart(matchID, gameID, turnID string, startPoints int, otherPlayerTurns []squelch.PlayerTurn, isFinalRound bool, state squelch.GameState) error {
+	return nil
+}
+
+func (p *browserPlayer) Choose(matchID, gameID, turnID string, dieValues string, options []squelch.ScoringOption, state squelch.GameState) (*squelch.PlayerChoice, error) {
+	select {
+	case c := <-p.choices:
+		return &c, nil
+	case <-p.quit:
+		return nil, errAbandoned
+	case <-time.After(idleTimeout):
+		return nil, errAbandoned
+	}
+}
+
+func (p *browserPlayer) Squelch(matchID, gameID, turnID string, dieValues string) error {
+	return nil
+}
@@ -0,0 +1,328 @@
+// Package web serves a single page browser UI to play squelch against bots
+// and to watch games live. Game events are streamed to the browser with
+// Server-Sent Events and the page itself is embedded in the binary.
+package web
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+	"github.com/segmentio/ksuid"
+)
+
+//go:embed static
+var static embed.FS
+
+const (
+	// sessionTTL is how long a new browser game waits for its event stream
+	// before it's forgotten
+	sessionTTL = time.Minute
+	// maxSessions is how many browser games can be open at once
+	maxSessions = 100
+)
+
+// Server is the play and spectate web server
+type Server struct {
+	targetScore int
+	bots        []string
+	newBot      func(spec string) (squelch.Player, error)
+	hub         *hub
+
+	sync        sync.Mutex
+	sessions    map[string]*session
+	sessionTTL  time.Duration
+	maxSessions int
+}
+
+// session is a single game between a browser player and a bot
+type session struct {
+	id      string
+	human   *browserPlayer
+	players []squelch.Player
+	start   sync.Once
+	// started is set once the browser is listening and the game is on
+	started bool
+}
+
+// close stops any bot programs the session started
+func (sess *session) close() {
+	for _, p := range sess.players {
+		if c, ok := p.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// NewServer makes a server where browser players can play any of the bot
+// specs, made with newBot, to the target score.
+func NewServer(targetScore int, bots []string, newBot func(spec string) (squelch.Player, error)) *Server {
+	return &Server{
+		targetScore: targetScore,
+		bots:        bots,
+		newBot:      newBot,
+		hub:         newHub(),
+		sessions:    make(map[string]*session),
+		sessionTTL:  sessionTTL,
+		maxSessions: maxSessions,
+	}
+}
+
+// Handler returns the http handler for the UI and its API
+func (s *Server) Handler() http.Handler {
+	assets, _ := fs.Sub(static, "static")
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(assets)))
+	mux.HandleFunc("/api/bots", s.handleBots)
+	mux.HandleFunc("/api/play", s.handlePlay)
+	mux.HandleFunc("/api/choose", s.handleChoose)
+	mux.HandleFunc("/api/events", s.handleEvents)
+	return mux
+}
+
+// Spectate returns an observer that streams a tournament to the browser.
+// While anyone is watching every roll and choice is held for delay so the
+// game can be followed. Browser players don't count as watching.
+func (s *Server) Spectate(delay time.Duration) squelch.Observer {
+	return squelch.EventFunc(func(e squelch.GameEvent) {
+		s.hub.publish(e)
+		switch e.Type {
+		case squelch.EventRoll, squelch.EventChoice, squelch.EventSquelch, squelch.EventReroll:
+			if delay > 0 && s.hub.spectators() > 0 {
+				time.Sleep(delay)
+			}
+		}
+	})
+}
+
+func (s *Server) handleBots(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"bots":        s.bots,
+		"targetScore": s.targetScore,
+	})
+}
+
+// playRequest starts a game against a bot
+type playRequest struct {
+	Name string `json:"name"`
+	Bot  string `json:"bot"`
+}
+
+func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("POST a game to play"))
+		return
+	}
+
+	var req playRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Name == "" {
+		req.Name = "Human"
+	}
+	if !s.knownBot(req.Bot) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown bot %q", req.Bot))
+		return
+	}
+
+	// hold a slot before starting the bot so parallel games can't go over
+	sess := &session{id: ksuid.New().String(), human: newBrowserPlayer(req.Name)}
+	s.sync.Lock()
+	full := len(s.sessions) >= s.maxSessions
+	if !full {
+		s.sessions[sess.id] = sess
+	}
+	s.sync.Unlock()
+	if full {
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many games in progress, try again later"))
+		return
+	}
+
+	bot, err := s.newBot(req.Bot)
+	if err != nil {
+		s.sync.Lock()
+		delete(s.sessions, sess.id)
+		s.sync.Unlock()
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// nobody else has the id until we answer
+	sess.players = []squelch.Player{sess.human, bot}
+	time.AfterFunc(s.sessionTTL, func() { s.expire(sess) })
+
+	// the game starts once the browser is listening for its events
+	writeJSON(w, http.StatusOK, map[string]interface{}{"matchId": sess.id, "botIndex": 0})
+}
+
+// chooseRequest is the browser player's choice for the current roll
+type chooseRequest struct {
+	MatchID string `json:"matchId"`
+	squelch.PlayerChoice
+}
+
+func (s *Server) handleChoose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("POST a choice"))
+		return
+	}
+
+	var req chooseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sess := s.session(req.MatchID)
+	if sess == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no game %q", req.MatchID))
+		return
+	}
+	if !sess.human.choose(req.PlayerChoice) {
+		writeError(w, http.StatusConflict, errors.New("it's not your turn to choose"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams game events. With a match parameter it streams just
+// that browser game and starts it, otherwise it streams everything.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	matchID := r.URL.Query().Get("match")
+	var sess *session
+	if matchID != "" {
+		if sess = s.startSession(matchID); sess == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no game %q", matchID))
+			return
+		}
+	}
+
+	sub := s.hub.subscribe(matchID)
+	defer s.hub.unsubscribe(sub)
+
+	if sess != nil {
+		// nobody is left to play once the browser goes away
+		defer sess.human.abandon()
+		sess.start.Do(func() { go s.run(sess) })
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case e := <-sub.events:
+			b, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: game\ndata: %s\n\n", b)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// run plays a browser game and forgets it once it's over
+func (s *Server) run(sess *session) {
+	defer func() {
+		s.sync.Lock()
+		delete(s.sessions, sess.id)
+		s.sync.Unlock()
+		sess.close()
+	}()
+
+	names := make([]string, len(sess.players))
+	for i, p := range sess.players {
+		info, err := p.Info()
+		if err != nil {
+			return
+		}
+		names[i] = info.Name
+	}
+
+	for i, p := range sess.players {
+		p.MatchStart(sess.id, 6, s.targetScore, 1, i, names, nil)
+	}
+
+	g := squelch.NewGame(sess.players, s.targetScore, sess.id, "1", 0)
+	g.AddObserver(squelch.EventFunc(s.hub.publish))
+	res, err := g.Run()
+
+	wins := make([]int, len(sess.players))
+	if err == nil {
+		wins[res.WinnerIndex]++
+	}
+	for _, p := range sess.players {
+		p.MatchEnd(sess.id, wins)
+	}
+}
+
+// expire forgets a browser game whose event stream never connected
+func (s *Server) expire(sess *session) {
+	s.sync.Lock()
+	expired := !sess.started && s.sessions[sess.id] == sess
+	if expired {
+		delete(s.sessions, sess.id)
+	}
+	s.sync.Unlock()
+
+	if expired {
+		sess.close()
+	}
+}
+
+func (s *Server) session(id string) *session {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+	return s.sessions[id]
+}
+
+// startSession marks the browser game as started so it won't expire
+func (s *Server) startSession(id string) *session {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+	sess := s.sessions[id]
+	if sess != nil {
+		sess.started = true
+	}
+	return sess
+}
+
+func (s *Server) knownBot(spec string) bool {
+	for _, b := range s.bots {
+		if b == spec {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
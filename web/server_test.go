@@ -0,0 +1,223 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dlclark/squelchbot-arena-go/localbot"
+	"github.com/dlclark/squelchbot-arena-go/squelch"
+)
+
+func newTestServer() *Server {
+	return NewServer(500, []string{"threshold"}, func(spec string) (squelch.Player, error) {
+		return localbot.NewBuiltinPlayer(spec)
+	})
+}
+
+func TestServer_Assets(t *testing.T) {
+	ts := httptest.NewServer(newTestServer().Handler())
+	defer ts.Close()
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%v: status incorrect, got %v", path, res.StatusCode)
+		}
+	}
+}
+
+func TestServer_Play(t *testing.T) {
+	ts := httptest.NewServer(newTestServer().Handler())
+	defer ts.Close()
+
+	if res := post(t, ts.URL+"/api/play", `{"bot":"nope"}`); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Unknown bot status incorrect, got %v", res.StatusCode)
+	}
+
+	res := post(t, ts.URL+"/api/play", `{"name":"Me","bot":"threshold"}`)
+	var start struct {
+		MatchID string `json:"matchId"`
+	}
+	json.NewDecoder(res.Body).Decode(&start)
+	res.Body.Close()
+	if start.MatchID == "" {
+		t.Fatalf("No match ID")
+	}
+
+	events, err := http.Get(ts.URL + "/api/events?match=" + start.MatchID)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer events.Body.Close()
+
+	// take the first option and stay every time until the game ends
+	done := make(chan squelch.GameEvent)
+	go func() {
+		sc := bufio.NewScanner(events.Body)
+		for sc.Scan() {
+			if !strings.HasPrefix(sc.Text(), "data: ") {
+				continue
+			}
+			var e squelch.GameEvent
+			json.Unmarshal([]byte(strings.TrimPrefix(sc.Text(), "data: ")), &e)
+			if e.MatchID != start.MatchID {
+				t.Errorf("Event for the wrong match: %+v", e)
+			}
+
+			switch {
+			case e.Type == squelch.EventRoll && e.BotIndex == 0:
+				body := `{"matchId":"` + start.MatchID + `","takeOptionId":"` + e.Options[0].ID + `","stay":true}`
+				if res := post(t, ts.URL+"/api/choose", body); res.StatusCode != http.StatusNoContent {
+					t.Errorf("Choose status incorrect, got %v", res.StatusCode)
+				}
+			case e.Type == squelch.EventGameEnd:
+				done <- e
+				return
+			}
+		}
+	}()
+
+	select {
+	case e := <-done:
+		if e.Err != "" || e.Players[0] != "Me" {
+			t.Errorf("Game end incorrect, got %+v", e)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Game never ended")
+	}
+
+	if res := post(t, ts.URL+"/api/choose", `{"matchId":"`+start.MatchID+`"}`); res.StatusCode != http.StatusNotFound {
+		t.Errorf("Finished game status incorrect, got %v", res.StatusCode)
+	}
+}
+
+func TestServer_Spectate(t *testing.T) {
+	s := newTestServer()
+	sub := s.hub.subscribe("")
+	defer s.hub.unsubscribe(sub)
+
+	a, _ := localbot.NewBuiltinPlayer("threshold")
+	b, _ := localbot.NewBuiltinPlayer("maxev")
+	tr := squelch.NewTournament(1, 2, 500, []squelch.Player{a, b})
+	tr.AddObserver(s.Spectate(0))
+	if _, err := tr.Run(); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var last squelch.GameEvent
+	for len(sub.events) > 0 {
+		last = <-sub.events
+	}
+	if last.Type != squelch.EventMatchEnd {
+		t.Errorf("Last event incorrect, got %+v", last)
+	}
+}
+
+func TestServer_Sessions(t *testing.T) {
+	s := newTestServer()
+	s.maxSessions = 2
+	s.sessionTTL = 50 * time.Millisecond
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	for i := 0; i < 2; i++ {
+		if res := post(t, ts.URL+"/api/play", `{"bot":"threshold"}`); res.StatusCode != http.StatusOK {
+			t.Fatalf("Play status incorrect, got %v", res.StatusCode)
+		}
+	}
+	if res := post(t, ts.URL+"/api/play", `{"bot":"threshold"}`); res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Play past the limit status incorrect, got %v", res.StatusCode)
+	}
+
+	// nobody connected to the games so they're forgotten
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.sync.Lock()
+		n := len(s.sessions)
+		s.sync.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Sessions should expire, %v left", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if res := post(t, ts.URL+"/api/play", `{"bot":"threshold"}`); res.StatusCode != http.StatusOK {
+		t.Errorf("Play after expiry status incorrect, got %v", res.StatusCode)
+	}
+}
+
+func TestServer_SessionsParallel(t *testing.T) {
+	var started atomic.Int32
+	s := NewServer(500, []string{"threshold"}, func(spec string) (squelch.Player, error) {
+		// a slow bot start leaves room for the other requests to race
+		started.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return localbot.NewBuiltinPlayer(spec)
+	})
+	s.maxSessions = 2
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	var ok atomic.Int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := http.Post(ts.URL+"/api/play", "application/json", bytes.NewBufferString(`{"bot":"threshold"}`))
+			if err != nil {
+				t.Errorf("Error: %v", err)
+				return
+			}
+			res.Body.Close()
+			if res.StatusCode == http.StatusOK {
+				ok.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := ok.Load(); n != 2 {
+		t.Errorf("Games started incorrect, want 2 got %v", n)
+	}
+	if n := started.Load(); n != 2 {
+		t.Errorf("Bots started incorrect, want 2 got %v", n)
+	}
+}
+
+func TestHub_Spectators(t *testing.T) {
+	h := newHub()
+	game := h.subscribe("match")
+	if n := h.spectators(); n != 0 {
+		t.Errorf("A browser game isn't a spectator, got %v", n)
+	}
+
+	all := h.subscribe("")
+	if n := h.spectators(); n != 1 {
+		t.Errorf("Spectators incorrect, got %v", n)
+	}
+	h.unsubscribe(all)
+	h.unsubscribe(game)
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	res, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return res
+}
@@ -0,0 +1,208 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+// ---- shared rendering ----
+
+function el(tag, cls, text) {
+	const e = document.createElement(tag);
+	if (cls) e.className = cls;
+	if (text !== undefined) e.textContent = text;
+	return e;
+}
+
+function renderBoard(board, ev, you) {
+	board.replaceChildren();
+	ev.players.forEach((name, i) => {
+		const p = el("div", "player" + (i === ev.botIndex && ev.type !== "gameend" ? " current" : ""));
+		p.append(el("div", "name", i === you ? name + " (you)" : name));
+		p.append(el("div", "score", ev.scores[i]));
+		board.append(p);
+	});
+}
+
+function renderDice(box, dice, taken) {
+	box.replaceChildren();
+	let left = taken || "";
+	for (const d of dice || "") {
+		const die = el("span", "die", d);
+		const at = left.indexOf(d);
+		if (at >= 0) {
+			die.classList.add("taken");
+			left = left.slice(0, at) + left.slice(at + 1);
+		}
+		box.append(die);
+	}
+}
+
+function describe(ev) {
+	const who = ev.players[ev.botIndex];
+	switch (ev.type) {
+	case "gamestart": return "Game " + ev.gameId + " started: " + ev.players.join(" vs ");
+	case "turnstart": return who + "'s turn" + (ev.isFinalRound ? " (final round)" : "");
+	case "roll": return who + " rolled " + ev.dice;
+	case "choice": return who + " took " + ev.take + " for " + ev.points +
+		(ev.stay ? " and stayed with " + ev.turnPoints : ", " + ev.turnPoints + " this turn");
+	case "squelch": return who + " rolled " + ev.dice + ": SQUELCH, lost " + ev.turnPoints;
+	case "rollover": return who + " scored every die, rolling all 6 again";
+	case "reroll": return who + " rolled " + ev.dice + ": squelch saved by a reroll";
+	case "overtime": return who + " reached the target, everyone else gets one last turn!";
+	case "gameend": return ev.error ? "Game ended with an error: " + ev.error :
+		ev.players[ev.winnerIndex] + " won!";
+	}
+	return ev.type;
+}
+
+// ---- play ----
+
+let playing = null;
+
+async function loadBots() {
+	const res = await fetch("api/bots");
+	const data = await res.json();
+	const sel = $("bot");
+	for (const b of data.bots) sel.append(new Option(b, b));
+}
+
+async function newGame(e) {
+	e.preventDefault();
+	if (playing) playing.source.close();
+
+	const res = await fetch("api/play", {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({name: $("name").value, bot: $("bot").value}),
+	});
+	const data = await res.json();
+	if (!res.ok) {
+		$("play-status").textContent = data.error;
+		return;
+	}
+
+	$("play-log").replaceChildren();
+	$("play-options").replaceChildren();
+	$("play-dice").replaceChildren();
+	$("play-board").classList.remove("hidden");
+
+	const source = new EventSource("api/events?match=" + encodeURIComponent(data.matchId));
+	playing = {matchId: data.matchId, you: data.botIndex, source};
+	source.addEventListener("game", (m) => onPlayEvent(JSON.parse(m.data)));
+}
+
+function onPlayEvent(ev) {
+	const status = $("play-status");
+	renderBoard($("play-board"), ev, playing.you);
+	status.classList.toggle("final", ev.isFinalRound);
+
+	const item = el("li", "", describe(ev));
+	$("play-log").prepend(item);
+
+	switch (ev.type) {
+	case "roll":
+		renderDice($("play-dice"), ev.dice);
+		if (ev.botIndex === playing.you) showOptions(ev);
+		break;
+	case "choice":
+		$("play-options").replaceChildren();
+		break;
+	case "squelch":
+	case "reroll":
+		renderDice($("play-dice"), ev.dice);
+		status.textContent = describe(ev);
+		break;
+	case "turnstart":
+		status.textContent = ev.botIndex === playing.you ? "Your turn" : describe(ev);
+		break;
+	case "gameend":
+		status.textContent = describe(ev);
+		playing.source.close();
+		break;
+	}
+}
+
+function showOptions(ev) {
+	const box = $("play-options");
+	box.replaceChildren();
+	$("play-status").textContent = "You have " + ev.turnPoints + " points this turn. Take an option:";
+
+	for (const o of ev.options) {
+		for (const stay of [false, true]) {
+			const b = el("button", "", (stay ? "Take " : "Roll on with ") + o.dieValues +
+				" (" + (ev.turnPoints + o.points) + (stay ? ", stay)" : ")"));
+			b.onclick = () => choose(o, stay);
+			b.onmouseenter = () => renderDice($("play-dice"), ev.dice, o.dieValues);
+			box.append(b);
+		}
+	}
+}
+
+async function choose(option, stay) {
+	$("play-options").replaceChildren();
+	const res = await fetch("api/choose", {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({matchId: playing.matchId, takeOptionId: option.id, stay}),
+	});
+	if (!res.ok) $("play-status").textContent = (await res.json()).error;
+}
+
+// ---- spectate ----
+
+let watching = null;
+const games = {};
+
+function watch() {
+	if (watching) return;
+	watching = new EventSource("api/events");
+	watching.addEventListener("game", (m) => onWatchEvent(JSON.parse(m.data)));
+}
+
+function onWatchEvent(ev) {
+	// games are shown on their own, not by match
+	if (ev.type === "matchstart" || ev.type === "matchend") return;
+
+	const key = ev.matchId + "/" + ev.gameId;
+	let g = games[key];
+	if (!g) {
+		const box = el("div", "game");
+		g = games[key] = {
+			box,
+			title: el("h3", "", ev.players.join(" vs ")),
+			board: el("div", "board"),
+			status: el("div", "status"),
+			dice: el("div", "dice"),
+		};
+		box.append(g.title, g.board, g.status, g.dice);
+		$("games").prepend(box);
+	}
+
+	renderBoard(g.board, ev, -1);
+	g.status.textContent = describe(ev);
+	g.status.classList.toggle("final", ev.isFinalRound);
+	if (ev.type === "roll" || ev.type === "squelch" || ev.type === "reroll") renderDice(g.dice, ev.dice);
+	if (ev.type === "choice") renderDice(g.dice, g.lastDice, ev.take);
+	if (ev.dice) g.lastDice = ev.dice;
+
+	if (ev.type === "gameend") {
+		// keep finished games around for a bit, then clear them
+		setTimeout(() => {
+			g.box.remove();
+			delete games[key];
+		}, 30000);
+	}
+}
+
+// ---- tabs ----
+
+function show(tab) {
+	$("play").classList.toggle("hidden", tab !== "play");
+	$("watch").classList.toggle("hidden", tab !== "watch");
+	$("tab-play").classList.toggle("active", tab === "play");
+	$("tab-watch").classList.toggle("active", tab === "watch");
+	if (tab === "watch") watch();
+}
+
+$("tab-play").onclick = () => show("play");
+$("tab-watch").onclick = () => show("watch");
+$("new-game").onsubmit = newGame;
+loadBots();
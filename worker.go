@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dlclark/squelchbot-arena-go/distrib"
+)
+
+// runWorker plays matches handed out by a tournament started with -coordinator
+func runWorker(args []string) {
+	fs := newFlagSet("worker", "-coordinator <url> [flags]", "Play matches handed out by a tournament run with -coordinator. The bots must match\nthe tournament's entrants in order.")
+	coordinator := fs.String("coordinator", "", "the coordinator's base URL, e.g. http://host:7070")
+	token := fs.String("token", "", "the coordinator's -worker-token")
+	par := fs.Int("par", 1, "matches to play at once")
+	auth := fs.String("auth", "", "JSON file of per-URL bot auth settings (secret, bearerToken, certFile, keyFile, caFile)")
+	bots := addBotFlags(fs)
+	lf := newLogFlags(fs)
+	fs.Parse(args)
+
+	if err := lf.setup(); err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+	if *coordinator == "" {
+		log.Fatalf("Invalid input: -coordinator is required")
+	}
+	if *token == "" {
+		log.Fatalf("Invalid input: -token is required")
+	}
+
+	p, err := makeBots(*bots, *auth)
+	if err != nil {
+		log.Fatalf("Invalid input: %v", err)
+	}
+	if len(p) < 2 {
+		log.Fatalf("Invalid input: at least 2 bots are required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Working for %v with %v entrants.\n", *coordinator, len(p))
+	if err := distrib.NewWorker(*coordinator, *token, p).Run(ctx, *par); err != nil {
+		fmt.Printf("Worker stopped: %v\n", err)
+		return
+	}
+	fmt.Println("Tournament over.")
+}